@@ -0,0 +1,425 @@
+package balance
+
+import (
+	"fmt"
+	"go-splitwise/model"
+	"go-splitwise/rates"
+	"go-splitwise/repository"
+	"sort"
+	"time"
+)
+
+// Engine computes balances between group members from items, item_splits and
+// transactions. It is the single source of the numbers shown in the app and
+// in reminder emails.
+type Engine struct {
+	groups repository.GroupRepository
+	splits repository.SplitRepository
+	rates  *rates.Store
+}
+
+// NewEngine creates a balance engine reading from the given repositories,
+// converting foreign currency amounts with rateStore
+func NewEngine(groups repository.GroupRepository, splits repository.SplitRepository, rateStore *rates.Store) *Engine {
+	return &Engine{
+		groups: groups,
+		splits: splits,
+		rates:  rateStore,
+	}
+}
+
+// Debts maps debtor to creditor to the gross amount the debtor owes the
+// creditor within a group, in the group's base currency
+type Debts map[int64]map[int64]int64
+
+// Add records that debtorID owes creditorID amount more
+func (d Debts) Add(debtorID, creditorID, amount int64) {
+	if d[debtorID] == nil {
+		d[debtorID] = make(map[int64]int64)
+	}
+	d[debtorID][creditorID] += amount
+}
+
+// Balance returns what otherUserID owes userID, negative when userID owes
+func (d Debts) Balance(userID, otherUserID int64) int64 {
+	return d[otherUserID][userID] - d[userID][otherUserID]
+}
+
+// Nets returns how much each user is owed in total, negative when they owe
+func (d Debts) Nets() map[int64]int64 {
+	nets := make(map[int64]int64)
+	for debtorID, creditors := range d {
+		for creditorID, amount := range creditors {
+			nets[debtorID] -= amount
+			nets[creditorID] += amount
+		}
+	}
+	return nets
+}
+
+// BaseCurrency returns the currency a group's balances are settled in
+func (e *Engine) BaseCurrency(groupID int64) (string, error) {
+	group, err := e.groups.Get(groupID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch group currency: %w", err)
+	}
+	if group.BaseCurrency == "" {
+		return rates.DefaultCurrency, nil
+	}
+	return group.BaseCurrency, nil
+}
+
+// GroupDebts returns every pairwise debt in a group along with the group's
+// base currency. Debts the repository could not convert with the rate pinned
+// on their item are converted at the latest rate.
+func (e *Engine) GroupDebts(groupID int64) (Debts, string, error) {
+	baseCurrency, err := e.BaseCurrency(groupID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := e.splits.GroupDebts(groupID, baseCurrency)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch group debts: %w", err)
+	}
+
+	converter := e.rates.NewConverter(baseCurrency)
+	debts := make(Debts)
+	for _, row := range rows {
+		converted := row.Converted
+		if row.Unconverted != 0 {
+			amount, err := converter.Convert(row.Unconverted, row.Currency, 0, "")
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to convert debt: %w", err)
+			}
+			converted += amount
+		}
+		debts.Add(row.DebtorID, row.CreditorID, converted)
+	}
+
+	return debts, baseCurrency, nil
+}
+
+// Pair returns what otherUserID owes userID in a group, negative when userID
+// owes
+func (e *Engine) Pair(groupID, userID, otherUserID int64) (int64, error) {
+	debts, _, err := e.GroupDebts(groupID)
+	if err != nil {
+		return 0, err
+	}
+	return debts.Balance(userID, otherUserID), nil
+}
+
+// ForUser returns the user's non-zero balances with each of otherUserIDs in a
+// group, in the order given, along with the group's base currency
+func (e *Engine) ForUser(groupID, userID int64, otherUserIDs []int64) ([]model.UserShare, string, error) {
+	debts, baseCurrency, err := e.GroupDebts(groupID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var balances []model.UserShare
+	for _, otherUserID := range otherUserIDs {
+		if otherUserID == userID {
+			continue
+		}
+		if amount := debts.Balance(userID, otherUserID); amount != 0 {
+			balances = append(balances, model.UserShare{
+				UserID:      otherUserID,
+				ShareAmount: amount,
+			})
+		}
+	}
+
+	return balances, baseCurrency, nil
+}
+
+// ForGroup returns the net balance of every member of a group, including
+// members who are settled up, along with the group's base currency
+func (e *Engine) ForGroup(groupID int64) (map[int64]int64, string, error) {
+	debts, baseCurrency, err := e.GroupDebts(groupID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	memberIDs, err := e.groups.MemberIDs(groupID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch group members: %w", err)
+	}
+
+	nets := debts.Nets()
+	for _, memberID := range memberIDs {
+		if _, ok := nets[memberID]; !ok {
+			nets[memberID] = 0
+		}
+	}
+
+	return nets, baseCurrency, nil
+}
+
+// Uncategorized labels expenses without a category in category reports
+const Uncategorized = "uncategorized"
+
+// Categories totals a group's spending per category and per member over the
+// expenses dated in [from, to), in the group's base currency. Categories
+// are ordered by total spent, largest first, and members by user ID.
+func (e *Engine) Categories(groupID int64, from, to time.Time) (*model.CategoryReport, error) {
+	baseCurrency, err := e.BaseCurrency(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := e.splits.CategorySpend(groupID, baseCurrency, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category spend: %w", err)
+	}
+
+	converter := e.rates.NewConverter(baseCurrency)
+	convert := func(amount int64, currency string) (int64, error) {
+		if amount == 0 {
+			return 0, nil
+		}
+		return converter.Convert(amount, currency, 0, "")
+	}
+
+	byCategory := make(map[string]map[int64]*model.MemberSpend)
+	byMember := make(map[int64]*model.MemberSpend)
+	for _, row := range rows {
+		paid, err := convert(row.UnconvertedPaid, row.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert category spend: %w", err)
+		}
+		owed, err := convert(row.UnconvertedOwed, row.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert category spend: %w", err)
+		}
+		paid += row.Paid
+		owed += row.Owed
+
+		category := row.Category
+		if category == "" {
+			category = Uncategorized
+		}
+		if byCategory[category] == nil {
+			byCategory[category] = make(map[int64]*model.MemberSpend)
+		}
+		for _, members := range []map[int64]*model.MemberSpend{byCategory[category], byMember} {
+			member, ok := members[row.UserID]
+			if !ok {
+				member = &model.MemberSpend{UserID: row.UserID}
+				members[row.UserID] = member
+			}
+			member.Paid += paid
+			member.Owed += owed
+		}
+	}
+
+	report := &model.CategoryReport{
+		GroupID:    groupID,
+		Currency:   baseCurrency,
+		Categories: []model.CategorySpend{},
+		Members:    sortedMembers(byMember),
+	}
+	for category, members := range byCategory {
+		spend := model.CategorySpend{Category: category, Members: sortedMembers(members)}
+		for _, member := range spend.Members {
+			spend.Total += member.Paid
+		}
+		report.Categories = append(report.Categories, spend)
+		report.Total += spend.Total
+	}
+	sort.Slice(report.Categories, func(a, b int) bool {
+		if report.Categories[a].Total != report.Categories[b].Total {
+			return report.Categories[a].Total > report.Categories[b].Total
+		}
+		return report.Categories[a].Category < report.Categories[b].Category
+	})
+
+	return report, nil
+}
+
+func sortedMembers(members map[int64]*model.MemberSpend) []model.MemberSpend {
+	sorted := make([]model.MemberSpend, 0, len(members))
+	for _, member := range members {
+		sorted = append(sorted, *member)
+	}
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].UserID < sorted[b].UserID })
+	return sorted
+}
+
+// Totals adds up per-group balances into one balance per other person and
+// currency, leaving out people the user is settled up with overall
+func Totals(balances []model.Balance) []model.Balance {
+	type key struct {
+		otherUserID int64
+		currency    string
+	}
+
+	index := make(map[key]int)
+	var totals []model.Balance
+	for _, b := range balances {
+		k := key{b.OtherUserID, b.Currency}
+		i, ok := index[k]
+		if !ok {
+			i = len(totals)
+			index[k] = i
+			totals = append(totals, model.Balance{
+				OtherUserID:   b.OtherUserID,
+				OtherUserName: b.OtherUserName,
+				Currency:      b.Currency,
+			})
+		}
+		totals[i].Amount += b.Amount
+	}
+
+	nonZero := []model.Balance{}
+	for _, total := range totals {
+		if total.Amount != 0 {
+			nonZero = append(nonZero, total)
+		}
+	}
+	sort.SliceStable(nonZero, func(a, b int) bool {
+		if nonZero[a].OtherUserID != nonZero[b].OtherUserID {
+			return nonZero[a].OtherUserID < nonZero[b].OtherUserID
+		}
+		return nonZero[a].Currency < nonZero[b].Currency
+	})
+	return nonZero
+}
+
+// NetAcrossGroups nets userID's per-group balances with otherUserID, as
+// returned for a single other person, wherever they owe each other in the same
+// currency in different groups. Every group is settled except one per
+// currency, which is left holding the whole remaining balance so a single
+// real payment settles the pair. It returns the offsetting transactions to
+// record and the balance left in each currency that was netted.
+func NetAcrossGroups(userID, otherUserID int64, balances []model.Balance) ([]model.Transactions, []model.Balance) {
+	byCurrency := make(map[string][]model.Balance)
+	var currencies []string
+	for _, b := range balances {
+		if b.OtherUserID != otherUserID || b.Amount == 0 {
+			continue
+		}
+		if _, ok := byCurrency[b.Currency]; !ok {
+			currencies = append(currencies, b.Currency)
+		}
+		byCurrency[b.Currency] = append(byCurrency[b.Currency], b)
+	}
+	sort.Strings(currencies)
+
+	// offset returns a transaction that changes what otherUserID owes userID
+	// in a group by delta
+	offset := func(groupID, delta int64) model.Transactions {
+		t := model.Transactions{GroupID: groupID, Kind: model.TransactionNetting}
+		if delta < 0 {
+			t.PayerID, t.UserID, t.Amount = otherUserID, userID, -delta
+		} else {
+			t.PayerID, t.UserID, t.Amount = userID, otherUserID, delta
+		}
+		return t
+	}
+
+	var transactions []model.Transactions
+	var remaining []model.Balance
+	for _, currency := range currencies {
+		groups := byCurrency[currency]
+
+		var total int64
+		owed, owing := false, false
+		for _, b := range groups {
+			total += b.Amount
+			owed = owed || b.Amount > 0
+			owing = owing || b.Amount < 0
+		}
+		if !owed || !owing {
+			continue
+		}
+
+		// Keep the remainder in the group already holding the largest share
+		// of it so the fewest amounts move
+		target := 0
+		for i, b := range groups {
+			if b.Amount*sign(total) > groups[target].Amount*sign(total) {
+				target = i
+			}
+		}
+
+		for i, b := range groups {
+			if i != target {
+				transactions = append(transactions, offset(b.GroupID, -b.Amount))
+			}
+		}
+		if delta := total - groups[target].Amount; delta != 0 {
+			transactions = append(transactions, offset(groups[target].GroupID, delta))
+		}
+
+		left := groups[target]
+		left.Amount = total
+		remaining = append(remaining, left)
+	}
+
+	return transactions, remaining
+}
+
+func sign(n int64) int64 {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Simplify turns net balances into a short list of transfers that settles
+// everyone. Debtors and creditors are each sorted once, largest first, and
+// walked in step: every transfer pays off the current debtor or creditor, or
+// both, before moving on. It needs at most one transfer fewer than the number
+// of people with a non-zero balance.
+func Simplify(nets map[int64]int64) []model.Transfer {
+	var debtors, creditors []model.UserShare
+	for userID, net := range nets {
+		if net < 0 {
+			debtors = append(debtors, model.UserShare{UserID: userID, ShareAmount: -net})
+		} else if net > 0 {
+			creditors = append(creditors, model.UserShare{UserID: userID, ShareAmount: net})
+		}
+	}
+
+	byAmount := func(shares []model.UserShare) func(a, b int) bool {
+		return func(a, b int) bool {
+			if shares[a].ShareAmount != shares[b].ShareAmount {
+				return shares[a].ShareAmount > shares[b].ShareAmount
+			}
+			return shares[a].UserID < shares[b].UserID
+		}
+	}
+	sort.Slice(debtors, byAmount(debtors))
+	sort.Slice(creditors, byAmount(creditors))
+
+	transfers := []model.Transfer{}
+	for d, c := 0, 0; d < len(debtors) && c < len(creditors); {
+		amount := debtors[d].ShareAmount
+		if creditors[c].ShareAmount < amount {
+			amount = creditors[c].ShareAmount
+		}
+
+		transfers = append(transfers, model.Transfer{
+			FromUserID: debtors[d].UserID,
+			ToUserID:   creditors[c].UserID,
+			Amount:     amount,
+		})
+
+		debtors[d].ShareAmount -= amount
+		creditors[c].ShareAmount -= amount
+		if debtors[d].ShareAmount == 0 {
+			d++
+		}
+		if creditors[c].ShareAmount == 0 {
+			c++
+		}
+	}
+
+	return transfers
+}
@@ -0,0 +1,457 @@
+package balance_test
+
+import (
+	"fmt"
+	"go-splitwise/balance"
+	"go-splitwise/model"
+	"go-splitwise/rates"
+	"go-splitwise/repository"
+	"reflect"
+	"testing"
+)
+
+// item is an expense as stored: who paid what and each user's resulting
+// balance on it, indexed by position in the fixture's users
+type item struct {
+	currency     string
+	rate         float64
+	rateCurrency string
+	paid         map[int]int64
+	balances     map[int]int64
+}
+
+// payment is a transaction from one fixture user to another
+type payment struct {
+	from, to int
+	amount   int64
+	reversed bool
+}
+
+// fixture is a group of four users backed by in-memory repositories
+type fixture struct {
+	repos   repository.Repositories
+	engine  *balance.Engine
+	groupID int64
+	users   []int64
+}
+
+func newFixture(t *testing.T, items []item, payments []payment) *fixture {
+	t.Helper()
+
+	repos := repository.NewInMemory()
+	store := rates.NewStore(repos.Rates)
+	err := store.Save([]rates.Rate{
+		{Date: "2026-01-01", Base: "EUR", Quote: "INR", Rate: 90},
+		{Date: "2026-02-01", Base: "EUR", Quote: "INR", Rate: 95},
+	})
+	if err != nil {
+		t.Fatalf("save rates: %v", err)
+	}
+
+	f := &fixture{repos: repos, engine: balance.NewEngine(repos.Groups, repos.Splits, store)}
+	group := model.Group{GroupName: "Flat"}
+	if err := repos.Groups.Create(&group); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	f.groupID = group.GroupID
+	for i := 0; i < 4; i++ {
+		userID, err := repos.Users.Create(fmt.Sprintf("User %d", i+1), fmt.Sprintf("user%d@example.com", i+1), "hash")
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		if err := repos.Groups.AddMember(f.groupID, userID); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+		f.users = append(f.users, userID)
+	}
+
+	for _, it := range items {
+		var amount int64
+		expense := model.Expense{
+			Description:          "Expense",
+			ExpenseDate:          "2026-01-15",
+			ExpenseType:          "EXACT",
+			Currency:             it.currency,
+			ExchangeRate:         it.rate,
+			ExchangeRateCurrency: it.rateCurrency,
+		}
+		for i := range f.users {
+			if paid, ok := it.paid[i]; ok {
+				expense.Payers = append(expense.Payers, model.ExpensePayer{UserID: f.users[i], Amount: paid})
+				amount += paid
+			}
+		}
+		expense.Amount = amount
+		expense.PayerID = expense.Payers[0].UserID
+
+		split := func(expense *model.Expense) error {
+			expense.Shares = nil
+			for i := range f.users {
+				if b, ok := it.balances[i]; ok {
+					expense.Shares = append(expense.Shares, model.UserShare{UserID: f.users[i], ShareAmount: b})
+				}
+			}
+			return nil
+		}
+		if err := repos.Items.Create(f.groupID, &expense, split); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	for _, p := range payments {
+		transaction := model.Transactions{GroupID: f.groupID, PayerID: f.users[p.from], UserID: f.users[p.to], Amount: p.amount}
+		if err := repos.Transactions.Create(&transaction); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+		if p.reversed {
+			if _, err := repos.Transactions.Reverse(transaction.ID, f.users[p.from]); err != nil {
+				t.Fatalf("reverse transaction: %v", err)
+			}
+		}
+	}
+	return f
+}
+
+// pair is what users[b] owes users[a], negative when users[a] owes
+type pair struct {
+	a, b   int
+	amount int64
+}
+
+var engineTests = []struct {
+	name     string
+	items    []item
+	payments []payment
+	nets     []int64
+	pairs    []pair
+}{
+	{
+		name:  "no expenses",
+		nets:  []int64{0, 0, 0, 0},
+		pairs: []pair{{0, 1, 0}},
+	},
+	{
+		name: "single payer",
+		items: []item{
+			{paid: map[int]int64{0: 900}, balances: map[int]int64{0: 600, 1: -300, 2: -300}},
+		},
+		nets:  []int64{600, -300, -300, 0},
+		pairs: []pair{{0, 1, 300}, {1, 0, -300}, {0, 2, 300}, {1, 2, 0}, {0, 3, 0}},
+	},
+	{
+		name: "multiple payers",
+		items: []item{
+			{paid: map[int]int64{0: 600, 1: 400}, balances: map[int]int64{0: 350, 1: 150, 2: -250, 3: -250}},
+		},
+		// Debtors take up the creditors' credit in user ID order
+		nets:  []int64{350, 150, -250, -250},
+		pairs: []pair{{0, 2, 250}, {0, 3, 100}, {1, 2, 0}, {1, 3, 150}, {0, 1, 0}},
+	},
+	{
+		name: "multiple payers with an uneven split",
+		items: []item{
+			{paid: map[int]int64{0: 4, 1: 3, 2: 3}, balances: map[int]int64{0: 4, 1: 3, 2: 3, 3: -10}},
+			{paid: map[int]int64{0: 5, 1: 5}, balances: map[int]int64{0: 2, 1: 2, 2: -2, 3: -2}},
+		},
+		nets:  []int64{6, 5, 1, -12},
+		pairs: []pair{{0, 3, 4}, {1, 3, 5}, {2, 3, 3}, {0, 2, 2}, {1, 2, 0}},
+	},
+	{
+		name: "currency converted at the pinned rate",
+		items: []item{
+			{currency: "USD", rate: 80, rateCurrency: "INR", paid: map[int]int64{1: 10}, balances: map[int]int64{1: 5, 2: -5}},
+		},
+		nets:  []int64{0, 400, -400, 0},
+		pairs: []pair{{1, 2, 400}},
+	},
+	{
+		name: "currency without a pinned rate converted at the latest rate",
+		items: []item{
+			{currency: "EUR", paid: map[int]int64{2: 10}, balances: map[int]int64{2: 5, 3: -5}},
+			{paid: map[int]int64{3: 100}, balances: map[int]int64{3: 50, 2: -50}},
+		},
+		nets:  []int64{0, 0, 425, -425},
+		pairs: []pair{{2, 3, 425}, {3, 2, -425}},
+	},
+	{
+		name: "payments settle debts",
+		items: []item{
+			{paid: map[int]int64{0: 900}, balances: map[int]int64{0: 600, 1: -300, 2: -300}},
+		},
+		payments: []payment{{from: 1, to: 0, amount: 300}, {from: 2, to: 0, amount: 100}},
+		nets:     []int64{200, 0, -200, 0},
+		pairs:    []pair{{0, 1, 0}, {0, 2, 200}},
+	},
+	{
+		name: "reversed payments no longer count",
+		items: []item{
+			{paid: map[int]int64{0: 900}, balances: map[int]int64{0: 600, 1: -300, 2: -300}},
+		},
+		payments: []payment{{from: 1, to: 0, amount: 300, reversed: true}, {from: 2, to: 0, amount: 300}},
+		nets:     []int64{300, -300, 0, 0},
+		pairs:    []pair{{0, 1, 300}, {0, 2, 0}},
+	},
+	{
+		name:     "payment without an expense",
+		payments: []payment{{from: 3, to: 1, amount: 50}},
+		nets:     []int64{0, -50, 0, 50},
+		pairs:    []pair{{3, 1, 50}, {1, 3, -50}},
+	},
+}
+
+func TestEngine(t *testing.T) {
+	for _, tt := range engineTests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, tt.items, tt.payments)
+
+			t.Run("ForGroup", func(t *testing.T) {
+				nets, currency, err := f.engine.ForGroup(f.groupID)
+				if err != nil {
+					t.Fatalf("ForGroup: %v", err)
+				}
+				if currency != rates.DefaultCurrency {
+					t.Errorf("currency %q, want %q", currency, rates.DefaultCurrency)
+				}
+				want := make(map[int64]int64)
+				for i, net := range tt.nets {
+					want[f.users[i]] = net
+				}
+				if !reflect.DeepEqual(nets, want) {
+					t.Errorf("nets %v, want %v", nets, want)
+				}
+			})
+
+			t.Run("Pair", func(t *testing.T) {
+				for _, p := range tt.pairs {
+					amount, err := f.engine.Pair(f.groupID, f.users[p.a], f.users[p.b])
+					if err != nil {
+						t.Fatalf("Pair: %v", err)
+					}
+					if amount != p.amount {
+						t.Errorf("user %d owes user %d %d, want %d", p.b+1, p.a+1, amount, p.amount)
+					}
+				}
+			})
+
+			t.Run("ForUser", func(t *testing.T) {
+				for i, userID := range f.users {
+					balances, _, err := f.engine.ForUser(f.groupID, userID, f.users)
+					if err != nil {
+						t.Fatalf("ForUser: %v", err)
+					}
+
+					var sum int64
+					for _, b := range balances {
+						if b.UserID == userID || b.ShareAmount == 0 {
+							t.Errorf("user %d has balance %+v", i+1, b)
+						}
+						pairAmount, err := f.engine.Pair(f.groupID, userID, b.UserID)
+						if err != nil {
+							t.Fatalf("Pair: %v", err)
+						}
+						if b.ShareAmount != pairAmount {
+							t.Errorf("user %d's balance with %d is %d but Pair gives %d", i+1, b.UserID, b.ShareAmount, pairAmount)
+						}
+						sum += b.ShareAmount
+					}
+					if sum != tt.nets[i] {
+						t.Errorf("user %d's balances add up to %d, want %d", i+1, sum, tt.nets[i])
+					}
+				}
+			})
+
+			t.Run("Simplify", func(t *testing.T) {
+				nets, _, err := f.engine.ForGroup(f.groupID)
+				if err != nil {
+					t.Fatalf("ForGroup: %v", err)
+				}
+				checkSettles(t, nets, balance.Simplify(nets))
+			})
+		})
+	}
+}
+
+// checkSettles asserts that transfers pay off nets exactly with at most one
+// transfer fewer than the number of people with a non-zero balance
+func checkSettles(t *testing.T, nets map[int64]int64, transfers []model.Transfer) {
+	t.Helper()
+
+	left := make(map[int64]int64)
+	nonZero := 0
+	for userID, net := range nets {
+		left[userID] = net
+		if net != 0 {
+			nonZero++
+		}
+	}
+	for _, transfer := range transfers {
+		if transfer.Amount <= 0 {
+			t.Errorf("transfer %+v is not positive", transfer)
+		}
+		left[transfer.FromUserID] += transfer.Amount
+		left[transfer.ToUserID] -= transfer.Amount
+	}
+	for userID, amount := range left {
+		if amount != 0 {
+			t.Errorf("user %d is left with %d after the transfers", userID, amount)
+		}
+	}
+	if nonZero > 0 && len(transfers) > nonZero-1 {
+		t.Errorf("%d transfers for %d people with a balance", len(transfers), nonZero)
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name string
+		nets map[int64]int64
+		want []model.Transfer
+	}{
+		{
+			name: "nobody",
+			nets: map[int64]int64{},
+			want: []model.Transfer{},
+		},
+		{
+			name: "everyone settled",
+			nets: map[int64]int64{1: 0, 2: 0},
+			want: []model.Transfer{},
+		},
+		{
+			name: "one debt",
+			nets: map[int64]int64{1: 100, 2: -100},
+			want: []model.Transfer{{FromUserID: 2, ToUserID: 1, Amount: 100}},
+		},
+		{
+			name: "chain collapses to one transfer",
+			nets: map[int64]int64{1: 100, 2: 0, 3: -100},
+			want: []model.Transfer{{FromUserID: 3, ToUserID: 1, Amount: 100}},
+		},
+		{
+			name: "largest debtor pays largest creditor first",
+			nets: map[int64]int64{1: 500, 2: 100, 3: -400, 4: -200},
+			want: []model.Transfer{
+				{FromUserID: 3, ToUserID: 1, Amount: 400},
+				{FromUserID: 4, ToUserID: 1, Amount: 100},
+				{FromUserID: 4, ToUserID: 2, Amount: 100},
+			},
+		},
+		{
+			name: "ties go to the lowest user ID",
+			nets: map[int64]int64{1: 100, 2: 100, 3: -100, 4: -100},
+			want: []model.Transfer{
+				{FromUserID: 3, ToUserID: 1, Amount: 100},
+				{FromUserID: 4, ToUserID: 2, Amount: 100},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := balance.Simplify(tt.nets)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Simplify(%v) = %+v, want %+v", tt.nets, got, tt.want)
+			}
+			checkSettles(t, tt.nets, got)
+		})
+	}
+}
+
+func TestNetAcrossGroups(t *testing.T) {
+	const user, other = 1, 2
+
+	tests := []struct {
+		name         string
+		balances     []model.Balance
+		transactions []model.Transactions
+		remaining    []model.Balance
+	}{
+		{
+			name: "a single group is left alone",
+			balances: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: 300},
+			},
+		},
+		{
+			name: "balances in the same direction are left alone",
+			balances: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: 300},
+				{OtherUserID: other, GroupID: 20, Currency: "INR", Amount: 100},
+			},
+		},
+		{
+			name: "opposite balances net into the larger group",
+			balances: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: 300},
+				{OtherUserID: other, GroupID: 20, Currency: "INR", Amount: -100},
+			},
+			transactions: []model.Transactions{
+				{GroupID: 20, PayerID: user, UserID: other, Amount: 100, Kind: model.TransactionNetting},
+				{GroupID: 10, PayerID: other, UserID: user, Amount: 100, Kind: model.TransactionNetting},
+			},
+			remaining: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: 200},
+			},
+		},
+		{
+			name: "balances that cancel out leave nothing owed",
+			balances: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: -250},
+				{OtherUserID: other, GroupID: 20, Currency: "INR", Amount: 250},
+			},
+			transactions: []model.Transactions{
+				{GroupID: 20, PayerID: other, UserID: user, Amount: 250, Kind: model.TransactionNetting},
+				{GroupID: 10, PayerID: user, UserID: other, Amount: 250, Kind: model.TransactionNetting},
+			},
+			remaining: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: 0},
+			},
+		},
+		{
+			name: "currencies are netted separately and other people ignored",
+			balances: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "USD", Amount: -40},
+				{OtherUserID: other, GroupID: 20, Currency: "USD", Amount: 10},
+				{OtherUserID: other, GroupID: 10, Currency: "INR", Amount: 500},
+				{OtherUserID: 3, GroupID: 20, Currency: "INR", Amount: -500},
+			},
+			transactions: []model.Transactions{
+				{GroupID: 20, PayerID: other, UserID: user, Amount: 10, Kind: model.TransactionNetting},
+				{GroupID: 10, PayerID: user, UserID: other, Amount: 10, Kind: model.TransactionNetting},
+			},
+			remaining: []model.Balance{
+				{OtherUserID: other, GroupID: 10, Currency: "USD", Amount: -30},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, remaining := balance.NetAcrossGroups(user, other, tt.balances)
+			if !reflect.DeepEqual(transactions, tt.transactions) {
+				t.Errorf("transactions %+v, want %+v", transactions, tt.transactions)
+			}
+			if !reflect.DeepEqual(remaining, tt.remaining) {
+				t.Errorf("remaining %+v, want %+v", remaining, tt.remaining)
+			}
+
+			// The transactions must leave exactly the remaining balances
+			after := make(map[string]int64)
+			for _, b := range tt.balances {
+				if b.OtherUserID == other {
+					after[b.Currency] += b.Amount
+				}
+			}
+			for _, transaction := range transactions {
+				if transaction.Amount <= 0 {
+					t.Errorf("transaction %+v is not positive", transaction)
+				}
+			}
+			for _, b := range remaining {
+				if after[b.Currency] != b.Amount {
+					t.Errorf("%s total %d but %d remains", b.Currency, after[b.Currency], b.Amount)
+				}
+			}
+		})
+	}
+}
@@ -1,79 +1,91 @@
 package controller
 
 import (
+	"bytes"
 	"crypto/rand"
-	"database/sql"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go-splitwise/balance"
 	"go-splitwise/cloudfareR2"
 	"go-splitwise/email"
 	model "go-splitwise/model"
+	"go-splitwise/rates"
+	"go-splitwise/repository"
+	"go-splitwise/rounding"
+	"go-splitwise/schedule"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/api/idtoken"
 )
 
-var db *sql.DB
-
-func configureDatabase() {
-	// Set explicit connection pool parameters
-	db.SetMaxOpenConns(20)                  // Limit total connections
-	db.SetMaxIdleConns(5)                   // Limit idle connections
-	db.SetConnMaxLifetime(30 * time.Minute) // Recycle connections periodically
-	db.SetConnMaxIdleTime(5 * time.Minute)  // Don't keep idle connections too long
-
-	// Verify connections work -> health checkup
-	if err := db.Ping(); err != nil {
-		log.Fatalf("Database connection failed: %v", err)
-	}
+// Server holds the dependencies shared by the HTTP handlers
+type Server struct {
+	users          repository.UserRepository
+	groups         repository.GroupRepository
+	items          repository.ItemRepository
+	splits         repository.SplitRepository
+	transactions   repository.TransactionRepository
+	sessions       repository.SessionRepository
+	memories       repository.MemoryRepository
+	passwordResets repository.PasswordResetRepository
+	reminders      repository.ReminderRepository
+	recurring      repository.RecurringExpenseRepository
+	exchangeRates  *rates.Store
+	balances       *balance.Engine
+	settling       groupLocks
 }
 
-func init() {
-
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("Error loading .env file, using existing environment variables")
-	}
-
-	databaseURL := os.Getenv("DATABASE_URL")
-
-	// so without this params and query are sent in two different parts but after using it first params are inserted in queries then sent to db so this is db drivers issue that happens sometimes when not able to bind it properly
-	if !strings.Contains(databaseURL, "?") {
-		databaseURL += "?disable_prepared_statements=true"
-	} else {
-		databaseURL += "&disable_prepared_statements=true"
-	}
-
-	db, err = sql.Open("postgres", databaseURL)
-	if err != nil {
-		panic(err)
+// NewServer creates a server reading and writing through repos and converting
+// currencies with exchangeRates
+func NewServer(repos repository.Repositories, exchangeRates *rates.Store) *Server {
+	return &Server{
+		users:          repos.Users,
+		groups:         repos.Groups,
+		items:          repos.Items,
+		splits:         repos.Splits,
+		transactions:   repos.Transactions,
+		sessions:       repos.Sessions,
+		memories:       repos.Memories,
+		passwordResets: repos.PasswordResets,
+		reminders:      repos.Reminders,
+		recurring:      repos.Recurring,
+		exchangeRates:  exchangeRates,
+		balances:       balance.NewEngine(repos.Groups, repos.Splits, exchangeRates),
 	}
-
-	configureDatabase()
-
-	fmt.Println("Successfully connected!")
 }
 
 type PasswordResetService struct {
 	emailService *email.EmailService
+	users        repository.UserRepository
+	resets       repository.PasswordResetRepository
 	codeTTL      time.Duration
 }
 
+// balanceReminderSender emails a user their balances
+type balanceReminderSender interface {
+	SendMonthlyBalanceReminder(recipient, userName string, balances []model.Balance) error
+}
+
 type ReminderService struct {
-	emailService *email.EmailService
+	emailService balanceReminderSender
+	server       *Server
 }
 
 func jsonError(w http.ResponseWriter, message string, code int) {
@@ -109,63 +121,138 @@ func validateUserInput(user model.UserRequest, w http.ResponseWriter) bool {
 	return true
 }
 
-func fetchAllGroupsByUserID(userID int64) ([]model.Group, error) {
-	rows, err := db.Query("SELECT group_id, name FROM groups WHERE group_id IN (SELECT group_id FROM group_users WHERE user_id = $1)", userID)
+func (s *Server) fetchAllGroupsByUserID(userID int64) ([]model.Group, error) {
+	groups, err := s.groups.ListByUser(userID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var groups []model.Group
-	for rows.Next() {
-		var g model.Group
-		if err := rows.Scan(&g.GroupID, &g.GroupName); err != nil {
-			return nil, err
-		}
-		groups = append(groups, g)
+	for i := range groups {
+		groups[i].RoundingPolicy = string(groupRoundingPolicy(&groups[i]))
+		groups[i].BaseCurrency = groupBaseCurrency(&groups[i])
 	}
 	return groups, nil
 }
 
-func insertUserIntoGroup(groupID int, userID int64) error {
-	query := `INSERT INTO group_users (group_id, user_id)
-	          VALUES ($1, $2)
-	          ON CONFLICT DO NOTHING`
-	_, err := db.Exec(query, groupID, userID)
-	return err
+// normalizePayers fills in Payers for single-payer expenses, validates that
+// the payments add up to the amount and makes the largest contributor the
+// item's primary payer
+func normalizePayers(expense *model.Expense) error {
+	if len(expense.Payers) == 0 {
+		expense.Payers = []model.ExpensePayer{{UserID: expense.PayerID, Amount: expense.Amount}}
+		return nil
+	}
+
+	var payers []model.ExpensePayer
+	index := make(map[int64]int)
+	var sum int64 = 0
+	for _, payer := range expense.Payers {
+		if payer.Amount < 0 {
+			return fmt.Errorf("payments must not be negative")
+		}
+		sum += payer.Amount
+		if i, ok := index[payer.UserID]; ok {
+			payers[i].Amount += payer.Amount
+			continue
+		}
+		index[payer.UserID] = len(payers)
+		payers = append(payers, payer)
+	}
+	if sum != expense.Amount {
+		return fmt.Errorf("sum of payments is not equal to the amount")
+	}
+
+	primary := payers[0]
+	for _, payer := range payers[1:] {
+		if payer.Amount > primary.Amount || (payer.Amount == primary.Amount && payer.UserID < primary.UserID) {
+			primary = payer
+		}
+	}
+	expense.PayerID = primary.UserID
+	expense.Payers = payers
+	return nil
 }
 
-func updateBalance(itemID int64, receiverID int64, amount int64) error {
-	query := `INSERT INTO item_splits (item_id, user_id, share)
-              VALUES ($1, $2, $3)
-              ON CONFLICT (item_id, user_id)
-              DO UPDATE SET share = EXCLUDED.share;`
-	_, err := db.Exec(query, itemID, receiverID, amount)
-	return err
+var errNotGroupMember = errors.New("user is not a member of the group")
+
+// checkExpenseMembers makes sure everyone paying for or sharing an expense
+// belongs to its group
+func (s *Server) checkExpenseMembers(groupID int64, expense *model.Expense) error {
+	checked := make(map[int64]bool)
+	check := func(userID int64) error {
+		if checked[userID] {
+			return nil
+		}
+		checked[userID] = true
+		member, err := s.groups.IsMember(groupID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to check group membership: %w", err)
+		}
+		if !member {
+			return errNotGroupMember
+		}
+		return nil
+	}
+
+	for _, payer := range expense.Payers {
+		if err := check(payer.UserID); err != nil {
+			return err
+		}
+	}
+	for _, share := range expense.Shares {
+		if err := check(share.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func splitEqually(expense *model.Expense) error {
-	shareAmount := expense.Amount / int64(len(expense.Shares))
+// recordOwedShares sets each user's balance for an expense, i.e. what they
+// paid minus what they owe
+func recordOwedShares(expense *model.Expense, owed []model.UserShare) {
+	if len(expense.Payers) == 0 {
+		expense.Payers = []model.ExpensePayer{{UserID: expense.PayerID, Amount: expense.Amount}}
+	}
+
 	var newShares []model.UserShare
-	var payerShare int64 = expense.Amount
-	for _, share := range expense.Shares {
-		userID := share.UserID
-		if userID != expense.PayerID {
-			err := updateBalance(expense.ExpenseID, userID, -shareAmount)
-			if err != nil {
-				return err
-			}
-			newShares = append(newShares, model.UserShare{UserID: userID, ShareAmount: -shareAmount})
-		} else {
-			payerShare -= shareAmount
+	index := make(map[int64]int)
+	addShare := func(userID, amount, rounding int64) {
+		if i, ok := index[userID]; ok {
+			newShares[i].ShareAmount += amount
+			newShares[i].RoundingAdjustment += rounding
+			return
 		}
+		index[userID] = len(newShares)
+		newShares = append(newShares, model.UserShare{UserID: userID, ShareAmount: amount, RoundingAdjustment: rounding})
 	}
-	err := updateBalance(expense.ExpenseID, expense.PayerID, payerShare)
-	if err != nil {
-		return err
+	for _, share := range owed {
+		addShare(share.UserID, -share.ShareAmount, share.RoundingAdjustment)
 	}
-	newShares = append(newShares, model.UserShare{UserID: expense.PayerID, ShareAmount: payerShare})
+	for _, payer := range expense.Payers {
+		addShare(payer.UserID, payer.Amount, 0)
+	}
+
 	expense.Shares = newShares
+}
+
+// allocateOwedShares divides amount between the weighted parts using the
+// expense's rounding policy and records how many units were left over
+func allocateOwedShares(expense *model.Expense, amount int64, parts []rounding.Part) []model.UserShare {
+	allocations, leftover := rounding.Allocate(rounding.Policy(expense.RoundingPolicy), amount, parts, expense.PayerID, expense.ExpenseID)
+	expense.RoundingRemainder = leftover
+
+	owed := make([]model.UserShare, len(allocations))
+	for i, allocation := range allocations {
+		owed[i] = model.UserShare{UserID: allocation.UserID, ShareAmount: allocation.Amount, RoundingAdjustment: allocation.Rounding}
+	}
+	return owed
+}
+
+func splitEqually(expense *model.Expense) error {
+	parts := make([]rounding.Part, len(expense.Shares))
+	for i, share := range expense.Shares {
+		parts[i] = rounding.Part{UserID: share.UserID, Weight: 1}
+	}
+	recordOwedShares(expense, allocateOwedShares(expense, expense.Amount, parts))
 	return nil
 }
 
@@ -174,67 +261,211 @@ func splitExactAmount(expense *model.Expense) error {
 	for _, share := range expense.Shares {
 		sum += int64(share.ShareAmount)
 	}
-	// fmt.Println(sum, expense.Amount)
 	if int64(sum) != expense.Amount {
 		return fmt.Errorf("sum of shares is not equal to the amount")
 	}
-	var newShares []model.UserShare
-	var payerShare int64 = expense.Amount
-	for i, share := range expense.Shares {
-		userID := share.UserID
-		if userID != expense.PayerID {
-			err := updateBalance(expense.ExpenseID, userID, -int64(expense.Shares[i].ShareAmount))
-			if err != nil {
-				return err
-			}
-			newShares = append(newShares, model.UserShare{UserID: userID, ShareAmount: -expense.Shares[i].ShareAmount})
-		} else {
-			payerShare -= int64(expense.Shares[i].ShareAmount)
-		}
-	}
-	err := updateBalance(expense.ExpenseID, expense.PayerID, payerShare)
-	if err != nil {
-		return err
-	}
-	newShares = append(newShares, model.UserShare{UserID: expense.PayerID, ShareAmount: payerShare})
-	expense.Shares = newShares
+	expense.RoundingRemainder = 0
+	recordOwedShares(expense, expense.Shares)
 	return nil
 }
 
 func splitByPercentage(expense *model.Expense) error {
 	var percentSum int64 = 0
 	for _, share := range expense.Shares {
+		if share.ShareAmount < 0 {
+			return fmt.Errorf("shares must not be negative")
+		}
+		if share.ShareAmount > 100 {
+			return fmt.Errorf("sum of shares is not equal to 100")
+		}
 		percentSum += share.ShareAmount
 	}
 	if percentSum != 100 {
 		return fmt.Errorf("sum of shares is not equal to 100")
 	}
-	var newShares []model.UserShare
-	var payerShare int64 = expense.Amount
+	parts := make([]rounding.Part, len(expense.Shares))
 	for i, share := range expense.Shares {
-		userID := share.UserID
-		if userID != expense.PayerID {
-			shareAmount := int64(expense.Shares[i].ShareAmount) * expense.Amount / 100
-			err := updateBalance(expense.ExpenseID, userID, -shareAmount)
-			if err != nil {
-				return err
-			}
-			newShares = append(newShares, model.UserShare{UserID: userID, ShareAmount: -shareAmount})
-		} else {
-			shareAmount := int64(expense.Shares[i].ShareAmount) * expense.Amount / 100
-			payerShare -= shareAmount
+		parts[i] = rounding.Part{UserID: share.UserID, Weight: share.ShareAmount}
+	}
+	recordOwedShares(expense, allocateOwedShares(expense, expense.Amount, parts))
+	return nil
+}
+
+// splitByShares divides the amount in proportion to each user's weight (e.g.
+// nights stayed)
+func splitByShares(expense *model.Expense) error {
+	var totalWeight int64 = 0
+	for _, share := range expense.Shares {
+		if share.ShareAmount < 0 {
+			return fmt.Errorf("shares must not be negative")
+		}
+		if share.ShareAmount > math.MaxInt64-totalWeight {
+			return fmt.Errorf("total shares are too large")
+		}
+		totalWeight += share.ShareAmount
+	}
+	if totalWeight == 0 {
+		return fmt.Errorf("total shares must be greater than zero")
+	}
+	parts := make([]rounding.Part, len(expense.Shares))
+	for i, share := range expense.Shares {
+		parts[i] = rounding.Part{UserID: share.UserID, Weight: share.ShareAmount}
+	}
+	recordOwedShares(expense, allocateOwedShares(expense, expense.Amount, parts))
+	return nil
+}
+
+// splitByAdjustment applies each user's +/- adjustment (e.g. an extra drink)
+// on top of an equal split of whatever is left
+func splitByAdjustment(expense *model.Expense) error {
+	var adjustmentSum int64 = 0
+	for _, share := range expense.Shares {
+		adjustmentSum += share.ShareAmount
+	}
+	remaining := expense.Amount - adjustmentSum
+	if remaining < 0 {
+		return fmt.Errorf("sum of adjustments exceeds the amount")
+	}
+
+	parts := make([]rounding.Part, len(expense.Shares))
+	adjustments := make(map[int64]int64)
+	for i, share := range expense.Shares {
+		parts[i] = rounding.Part{UserID: share.UserID, Weight: 1}
+		adjustments[share.UserID] += share.ShareAmount
+	}
+
+	owed := allocateOwedShares(expense, remaining, parts)
+	for i := range owed {
+		owed[i].ShareAmount += adjustments[owed[i].UserID]
+		if owed[i].ShareAmount < 0 {
+			return fmt.Errorf("adjusted share must not be negative")
 		}
 	}
-	err := updateBalance(expense.ExpenseID, expense.PayerID, payerShare)
+
+	recordOwedShares(expense, owed)
+	return nil
+}
+
+// groupRoundingPolicy returns the rounding policy configured for a group
+func groupRoundingPolicy(group *model.Group) rounding.Policy {
+	policy := rounding.Policy(group.RoundingPolicy)
+	if !rounding.IsValid(policy) {
+		return rounding.Default
+	}
+	return policy
+}
+
+// groupBaseCurrency returns the currency a group's balances are settled in
+func groupBaseCurrency(group *model.Group) string {
+	if group.BaseCurrency == "" {
+		return rates.DefaultCurrency
+	}
+	return group.BaseCurrency
+}
+
+// resolveExpenseCurrency defaults an expense to its group's base currency and
+// pins the rate into the base currency in effect on date, so later rate
+// updates don't shift old balances
+func (s *Server) resolveExpenseCurrency(group *model.Group, expense *model.Expense, date time.Time) error {
+	baseCurrency := groupBaseCurrency(group)
+	if strings.TrimSpace(expense.Currency) == "" {
+		expense.Currency = baseCurrency
+	}
+
+	var err error
+	expense.Currency, err = rates.NormalizeCode(expense.Currency)
 	if err != nil {
 		return err
 	}
-	newShares = append(newShares, model.UserShare{UserID: expense.PayerID, ShareAmount: payerShare})
-	expense.Shares = newShares
+	expense.ExchangeRate, err = s.exchangeRates.On(date, expense.Currency, baseCurrency)
+	if err != nil {
+		return err
+	}
+	expense.ExchangeRateCurrency = baseCurrency
 	return nil
 }
 
+var errInvalidExpenseDate = errors.New("invalid expense date")
+
+// resolveExpenseDate normalizes an expense's date to YYYY-MM-DD, defaulting to
+// the day of fallback, and returns it
+func resolveExpenseDate(expense *model.Expense, fallback time.Time) (time.Time, error) {
+	value := strings.TrimSpace(expense.ExpenseDate)
+	if value == "" {
+		value = fallback.Format(time.DateOnly)
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, errInvalidExpenseDate
+	}
+	expense.ExpenseDate = date.Format(time.DateOnly)
+	return date, nil
+}
+
+// builtinCategories are available to every group on top of its own
+var builtinCategories = []string{"food", "travel", "rent", "utilities"}
+
+var errUnknownCategory = errors.New("unknown category")
+
+// normalizeCategory lowercases a category name and collapses its whitespace
+func normalizeCategory(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// groupCategories returns the built-in categories followed by the group's own
+func (s *Server) groupCategories(groupID int64) ([]model.Category, error) {
+	custom, err := s.groups.Categories(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]model.Category, 0, len(builtinCategories)+len(custom))
+	for _, name := range builtinCategories {
+		categories = append(categories, model.Category{Name: name})
+	}
+	for _, name := range custom {
+		categories = append(categories, model.Category{Name: name, Custom: true})
+	}
+	return categories, nil
+}
+
+// resolveCategory normalizes an expense category and checks that the group
+// has it; an empty category leaves the expense uncategorized
+func (s *Server) resolveCategory(groupID int64, category *string) error {
+	*category = normalizeCategory(*category)
+	if *category == "" {
+		return nil
+	}
+
+	categories, err := s.groupCategories(groupID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	for _, c := range categories {
+		if c.Name == *category {
+			return nil
+		}
+	}
+	return errUnknownCategory
+}
+
+// calculateBalances computes the shares of an expense; it is passed to the
+// item repository so they are stored together with the item
 func calculateBalances(expense *model.Expense) error {
+	if expense.Amount <= 0 {
+		return fmt.Errorf("amount must be greater than zero")
+	}
+	if len(expense.Shares) == 0 {
+		return fmt.Errorf("expense has no shares")
+	}
+	seen := make(map[int64]bool)
+	for _, share := range expense.Shares {
+		if seen[share.UserID] {
+			return fmt.Errorf("user has more than one share")
+		}
+		seen[share.UserID] = true
+	}
 
 	switch expense.ExpenseType {
 	case "EQUAL":
@@ -243,48 +474,77 @@ func calculateBalances(expense *model.Expense) error {
 		return splitExactAmount(expense)
 	case "PERCENTAGE":
 		return splitByPercentage(expense)
+	case "SHARES":
+		return splitByShares(expense)
+	case "ADJUSTMENT":
+		return splitByAdjustment(expense)
 	}
-	return nil
+	return fmt.Errorf("unsupported expense type %q", expense.ExpenseType)
 }
 
-func getUserByEmail(email string) (*model.UserRequest, error) {
-	user := &model.UserRequest{}
-	query := "SELECT user_id FROM users WHERE email = $1"
-	err := db.QueryRow(query, email).Scan(&user.UserID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
-		}
-		return nil, err
+// writeExpenseError maps an error from calculateBalances to a response
+func writeExpenseError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "amount must be greater than zero") {
+		jsonError(w, "The amount must be greater than zero.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "sum of shares is not equal to the amount") {
+		jsonError(w, "The sum of individual shares must equal the total amount.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "sum of shares is not equal to 100") {
+		jsonError(w, "When splitting by percentage, all percentages must add up to 100%.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "sum of adjustments exceeds the amount") {
+		jsonError(w, "The adjustments add up to more than the total amount.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "adjusted share must not be negative") {
+		jsonError(w, "An adjustment leaves someone with a negative share.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "shares must not be negative") {
+		jsonError(w, "Shares cannot be negative.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "total shares are too large") {
+		jsonError(w, "The shares add up to more than can be stored. Please use smaller numbers.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "total shares must be greater than zero") {
+		jsonError(w, "At least one person must have a share greater than zero.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "user has more than one share") {
+		jsonError(w, "Each person can only be listed once in the split.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "expense has no shares") {
+		jsonError(w, "Please select at least one person to split the expense with.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "sum of payments is not equal to the amount") {
+		jsonError(w, "The amounts paid must add up to the total amount.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "payments must not be negative") {
+		jsonError(w, "Amounts paid cannot be negative.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "invalid currency code") {
+		jsonError(w, "Please enter a valid 3-letter currency code.", http.StatusBadRequest)
+	} else if errors.Is(err, errInvalidExpenseDate) {
+		jsonError(w, "Please give the expense date as YYYY-MM-DD.", http.StatusBadRequest)
+	} else if errors.Is(err, errNotGroupMember) {
+		jsonError(w, "Everyone paying for or sharing the expense must be a member of the group.", http.StatusBadRequest)
+	} else if errors.Is(err, errUnknownCategory) {
+		jsonError(w, "Please choose one of the group's categories.", http.StatusBadRequest)
+	} else if errors.Is(err, rates.ErrRateNotFound) {
+		jsonError(w, "There is no exchange rate for this currency yet. Please add one and try again.", http.StatusBadRequest)
+	} else if strings.Contains(err.Error(), "unsupported expense type") {
+		jsonError(w, "Please choose a valid split type.", http.StatusBadRequest)
+	} else {
+		log.Printf("Error calculating balances: %v", err)
+		jsonError(w, "Failed to calculate balances. Please check your expense details and try again.", http.StatusInternalServerError)
 	}
-	return user, nil
 }
 
-func newPasswordResetService(emailService *email.EmailService) *PasswordResetService {
+func (s *Server) newPasswordResetService(emailService *email.EmailService) *PasswordResetService {
 	return &PasswordResetService{
 		emailService: emailService,
+		users:        s.users,
+		resets:       s.passwordResets,
 		codeTTL:      15 * time.Minute,
 	}
 }
 
-func newReminderService(emailService *email.EmailService) *ReminderService {
+func (s *Server) newReminderService(emailService balanceReminderSender) *ReminderService {
 	return &ReminderService{
 		emailService: emailService,
+		server:       s,
 	}
 }
 
-func savePasswordReset(reset model.PasswordReset) error {
-	query := `
-		INSERT INTO password_resets (user_id, email, code, expires_at, used)
-		VALUES ($1, $2, $3, $4, $5)
-	`
-	_, err := db.Exec(query, reset.UserID, reset.Email, reset.Code, reset.ExpiresAt, reset.Used)
-	return err
-}
-
 // method attached to passwordresetservice
 func (s *PasswordResetService) RequestPasswordReset(emailAddress string) error {
-	user, err := getUserByEmail(emailAddress)
+	user, err := s.users.GetByEmail(emailAddress)
 	if err != nil {
 		return errors.New("user not found")
 	}
@@ -301,7 +561,7 @@ func (s *PasswordResetService) RequestPasswordReset(emailAddress string) error {
 		Used:      false,
 	}
 
-	if err := savePasswordReset(reset); err != nil {
+	if err := s.resets.Create(reset); err != nil {
 		return err
 	}
 
@@ -314,56 +574,9 @@ func (s *PasswordResetService) RequestPasswordReset(emailAddress string) error {
 	return nil
 }
 
-func getLatestPasswordResetByEmail(emailAddress string) (*model.PasswordReset, error) {
-	reset := &model.PasswordReset{} // created an instance with fields initalized to default values and return the reference
-	query := `
-		SELECT id, user_id, email, code, expires_at, used, created_at
-		FROM password_resets
-		WHERE email = $1
-		ORDER BY created_at DESC
-		LIMIT 1
-	`
-	err := db.QueryRow(query, emailAddress).Scan(
-		&reset.ID, &reset.UserID, &reset.Email, &reset.Code,
-		&reset.ExpiresAt, &reset.Used, &reset.CreatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("no reset request found")
-		}
-		return nil, err
-	}
-	return reset, nil
-}
-
-func updateUserPassword(userID int64, hashedPassword string) error {
-	query := "UPDATE users SET password = $1 WHERE user_id = $2"
-	result, err := db.Exec(query, hashedPassword, userID)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return errors.New("no user found with that ID")
-	}
-
-	return nil
-}
-
-func markPasswordResetCodeAsUsed(resetID int64) error {
-	query := "UPDATE password_resets SET used = true WHERE id = $1"
-	_, err := db.Exec(query, resetID)
-	return err
-}
-
 func (s *PasswordResetService) ValidateCodeAndResetPassword(email, code, newPassword string) error {
 	// Get the most recent reset request for this email
-	reset, err := getLatestPasswordResetByEmail(email)
+	reset, err := s.resets.Latest(email)
 	if err != nil {
 		return errors.New("invalid or expired password reset request, please try again")
 	}
@@ -390,19 +603,19 @@ func (s *PasswordResetService) ValidateCodeAndResetPassword(email, code, newPass
 	}
 
 	// Update user password in DB
-	if err := updateUserPassword(reset.UserID, string(hashedPassword)); err != nil {
+	if err := s.users.UpdatePassword(reset.UserID, string(hashedPassword)); err != nil {
 		return errors.New("failed to update password, please try again later")
 	}
 
 	// Mark code as used
-	if err := markPasswordResetCodeAsUsed(reset.ID); err != nil {
+	if err := s.resets.MarkUsed(reset.ID); err != nil {
 		return errors.New("failed to complete password reset, please try again")
 	}
 
 	return nil
 }
 
-func RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.RequestPasswordResetRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		jsonError(w, "Invalid request. Please check your input and try again.", http.StatusBadRequest)
@@ -419,7 +632,7 @@ func RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resetService := newPasswordResetService(emailService)
+	resetService := s.newPasswordResetService(emailService)
 	err = resetService.RequestPasswordReset(req.Email)
 	if err != nil {
 		w.WriteHeader(http.StatusOK)
@@ -435,7 +648,7 @@ func RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func ResetPasswordCompleteHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) ResetPasswordCompleteHandler(w http.ResponseWriter, r *http.Request) {
 	var req model.ResetPasswordCompleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		jsonError(w, "Invalid request. Please check your input and try again.", http.StatusBadRequest)
@@ -452,7 +665,7 @@ func ResetPasswordCompleteHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resetService := newPasswordResetService(emailService)
+	resetService := s.newPasswordResetService(emailService)
 	err = resetService.ValidateCodeAndResetPassword(req.Email, req.Code, req.NewPassword)
 	if err != nil {
 		jsonError(w, err.Error(), http.StatusBadRequest)
@@ -465,7 +678,7 @@ func ResetPasswordCompleteHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func RegisterUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	var user model.UserRequest
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
@@ -483,10 +696,9 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING user_id`
-	err = db.QueryRow(query, user.Name, user.Email, string(hashedPassword)).Scan(&user.UserID)
+	user.UserID, err = s.users.Create(user.Name, user.Email, string(hashedPassword))
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate key") {
+		if errors.Is(err, repository.ErrDuplicate) {
 			jsonError(w, "This email address is already registered. Please use a different email or login to your account.", http.StatusInternalServerError)
 		} else {
 			jsonError(w, "We couldn't create your account. Please try again later.", http.StatusInternalServerError)
@@ -502,30 +714,27 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 
 	// Store the session in the database with expiration time
 	expiresAt := time.Now().Add(24 * time.Hour)
-	_, err = db.Exec(
-		"INSERT INTO sessions (session_id, user_id, expires_at) VALUES ($1, $2, $3)",
-		sessionToken, registeredUser.UserID, expiresAt,
-	)
+	err = s.sessions.Create(sessionToken, registeredUser.UserID, expiresAt)
 	if err != nil {
 		jsonError(w, "Failed to create session. Please try logging in again.", http.StatusInternalServerError)
 		return
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    sessionToken,
-		Expires:  expiresAt,
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteNoneMode,
-		Partitioned:true,
+		Name:        "session_token",
+		Value:       sessionToken,
+		Expires:     expiresAt,
+		HttpOnly:    true,
+		Secure:      true,
+		Path:        "/",
+		SameSite:    http.SameSiteNoneMode,
+		Partitioned: true,
 	})
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(registeredUser)
 }
 
-func LoginUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) LoginUser(w http.ResponseWriter, r *http.Request) {
 	var user model.UserRequest
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
@@ -533,18 +742,13 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var registeredUser model.UserResponse
-	var hashedPassword string
-
-	err = db.QueryRow("SELECT user_id, name, email, password FROM users WHERE email = $1",
-		user.Email).Scan(&registeredUser.UserID, &registeredUser.Name, &registeredUser.Email, &hashedPassword)
-
+	registeredUser, err := s.users.GetByEmail(user.Email)
 	if err != nil {
 		jsonError(w, "Invalid email or password. Please check your credentials and try again.", http.StatusUnauthorized)
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(user.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(registeredUser.Password), []byte(user.Password))
 	if err != nil {
 		jsonError(w, "Invalid email or password. Please check your credentials and try again.", http.StatusUnauthorized)
 		return
@@ -559,30 +763,27 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 	} else {
 		expiresAt = time.Now().Add(24 * time.Hour) // 1 day
 	}
-	_, err = db.Exec(
-		"INSERT INTO sessions (session_id, user_id, expires_at) VALUES ($1, $2, $3)",
-		sessionToken, registeredUser.UserID, expiresAt,
-	)
+	err = s.sessions.Create(sessionToken, registeredUser.UserID, expiresAt)
 	if err != nil {
 		jsonError(w, "Failed to create session. Please try logging in again.", http.StatusInternalServerError)
 		return
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    sessionToken,
-		Expires:  expiresAt,
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteNoneMode,
-		Partitioned:true,
+		Name:        "session_token",
+		Value:       sessionToken,
+		Expires:     expiresAt,
+		HttpOnly:    true,
+		Secure:      true,
+		Path:        "/",
+		SameSite:    http.SameSiteNoneMode,
+		Partitioned: true,
 	})
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(registeredUser)
 }
 
-func HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
 
 	var body struct {
 		Token string `json:"token"`
@@ -602,12 +803,15 @@ func HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
 	googleID := claims["sub"].(string)
 
 	var user model.UserResponse
-	err = db.QueryRow("SELECT user_id, name FROM users WHERE google_id = $1", googleID).Scan(&user.UserID, &user.Name)
-	if err == sql.ErrNoRows {
+	existing, err := s.users.GetByGoogleID(googleID)
+	if err == nil {
+		user.UserID, user.Name = existing.UserID, existing.Name
+	} else if errors.Is(err, repository.ErrNotFound) {
 
-		err = db.QueryRow("SELECT user_id, name FROM users WHERE email = $1", email).Scan(&user.UserID, &user.Name)
-		if err == sql.ErrNoRows {
-			err = db.QueryRow("INSERT INTO users (name, email, google_id) VALUES ($1, $2, $3) RETURNING user_id, name", name, email, googleID).Scan(&user.UserID, &user.Name)
+		existing, err = s.users.GetByEmail(email)
+		if errors.Is(err, repository.ErrNotFound) {
+			user.Name = name
+			user.UserID, err = s.users.CreateWithGoogle(name, email, googleID)
 			if err != nil {
 				jsonError(w, "Failed to create account. Please try again later.", http.StatusInternalServerError)
 				return
@@ -616,7 +820,8 @@ func HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
 			jsonError(w, "Failed to retrieve account information. Please try again later.", http.StatusInternalServerError)
 			return
 		} else {
-			_, err = db.Exec("UPDATE users SET google_id = $1 WHERE user_id = $2", googleID, user.UserID)
+			user.UserID, user.Name = existing.UserID, existing.Name
+			err = s.users.LinkGoogle(user.UserID, googleID)
 			if err != nil {
 				jsonError(w, "Failed to update account. Please try again later.", http.StatusInternalServerError)
 				return
@@ -629,24 +834,21 @@ func HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
 
 	// Store the session in the database with expiration time
 	expiresAt := time.Now().Add(24 * time.Hour)
-	_, err = db.Exec(
-		"INSERT INTO sessions (session_id, user_id, expires_at) VALUES ($1, $2, $3)",
-		sessionToken, user.UserID, expiresAt,
-	)
+	err = s.sessions.Create(sessionToken, user.UserID, expiresAt)
 	if err != nil {
 		jsonError(w, "Failed to create session. Please try logging in again.", http.StatusInternalServerError)
 		return
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    sessionToken,
-		Expires:  expiresAt,
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteNoneMode,
-		Partitioned:true,
+		Name:        "session_token",
+		Value:       sessionToken,
+		Expires:     expiresAt,
+		HttpOnly:    true,
+		Secure:      true,
+		Path:        "/",
+		SameSite:    http.SameSiteNoneMode,
+		Partitioned: true,
 	})
 
 	user.Email = email
@@ -654,22 +856,22 @@ func HandleGoogleAuth(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-func Logout(w http.ResponseWriter, r *http.Request) {
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session_token")
 	if err == nil {
-		_, _ = db.Exec("DELETE FROM sessions WHERE session_id = $1", cookie.Value)
+		_ = s.sessions.Delete(cookie.Value)
 	}
 
 	// Remove the cookie
 	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour),
-		HttpOnly: true,
-		Secure:   true,
-		Path:     "/",
-		SameSite: http.SameSiteNoneMode,
-		Partitioned:true,
+		Name:        "session_token",
+		Value:       "",
+		Expires:     time.Now().Add(-time.Hour),
+		HttpOnly:    true,
+		Secure:      true,
+		Path:        "/",
+		SameSite:    http.SameSiteNoneMode,
+		Partitioned: true,
 	})
 
 	w.Header().Set("Content-Type", "application/json")
@@ -678,7 +880,7 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func GetLoggedInUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetLoggedInUser(w http.ResponseWriter, r *http.Request) {
 	// Get the session token cookie
 	cookie, err := r.Cookie("session_token")
 
@@ -693,14 +895,9 @@ func GetLoggedInUser(w http.ResponseWriter, r *http.Request) {
 	sessionToken := cookie.Value
 
 	// Look up the session in the database
-	var userID int64
-	var expiresAt time.Time
-	err = db.QueryRow(
-		"SELECT user_id, expires_at FROM sessions WHERE session_id = $1",
-		sessionToken,
-	).Scan(&userID, &expiresAt)
+	userID, expiresAt, err := s.sessions.Get(sessionToken)
 
-	if err == sql.ErrNoRows {
+	if errors.Is(err, repository.ErrNotFound) {
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"message": "Invalid session",
@@ -717,7 +914,7 @@ func GetLoggedInUser(w http.ResponseWriter, r *http.Request) {
 	// Check if session has expired
 	if time.Now().After(expiresAt) {
 		// Delete the expired session
-		_, _ = db.Exec("DELETE FROM sessions WHERE session_id = $1", sessionToken)
+		_ = s.sessions.Delete(sessionToken)
 
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -726,10 +923,7 @@ func GetLoggedInUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user model.UserResponse
-	err = db.QueryRow("SELECT user_id, name, email FROM users WHERE user_id = $1", userID).Scan(
-		&user.UserID, &user.Name, &user.Email,
-	)
+	user, err := s.users.Get(userID)
 
 	if err != nil {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -744,7 +938,7 @@ func GetLoggedInUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(user)
 }
 
-func UpdatePassword(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email       string `json:"email"`
 		NewPassword string `json:"newPassword"`
@@ -762,7 +956,7 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = db.Exec("UPDATE users SET password = $1 WHERE email = $2", string(hashedPassword), input.Email)
+	err = s.users.UpdatePasswordByEmail(input.Email, string(hashedPassword))
 	if err != nil {
 		jsonError(w, "Failed to update password. Please try again later.", http.StatusInternalServerError)
 		return
@@ -774,14 +968,13 @@ func UpdatePassword(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func CleanupExpiredSessions() {
-	_, err := db.Exec("DELETE FROM sessions WHERE expires_at < NOW()")
-	if err != nil {
+func (s *Server) CleanupExpiredSessions() {
+	if err := s.sessions.DeleteExpired(); err != nil {
 		log.Printf("Error cleaning up expired sessions: %v", err)
 	}
 }
 
-func GetGroupDetailsByUserId(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetGroupDetailsByUserId(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userIDStr := vars["userId"]
 
@@ -792,7 +985,7 @@ func GetGroupDetailsByUserId(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	groups, err := fetchAllGroupsByUserID(int64(userID))
+	groups, err := s.fetchAllGroupsByUserID(int64(userID))
 
 	if err != nil {
 		jsonError(w, "Failed to fetch your groups. Please try again later.", http.StatusInternalServerError)
@@ -802,7 +995,7 @@ func GetGroupDetailsByUserId(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(groups)
 }
 
-func CreateGroup(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userIDStr := vars["userId"]
 	userID, err := strconv.Atoi(userIDStr)
@@ -824,13 +1017,21 @@ func CreateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `INSERT INTO groups (name) VALUES ($1) RETURNING group_id`
-	err = db.QueryRow(query, group.GroupName).Scan(&group.GroupID)
+	if strings.TrimSpace(group.BaseCurrency) == "" {
+		group.BaseCurrency = rates.DefaultCurrency
+	}
+	group.BaseCurrency, err = rates.NormalizeCode(group.BaseCurrency)
+	if err != nil {
+		jsonError(w, "Please enter a valid 3-letter currency code.", http.StatusBadRequest)
+		return
+	}
+
+	err = s.groups.Create(&group)
 	if err != nil {
 		jsonError(w, "Failed to create group. Please try again later.", http.StatusInternalServerError)
 		return
 	}
-	err = insertUserIntoGroup(int(group.GroupID), int64(userID))
+	err = s.groups.AddMember(group.GroupID, int64(userID))
 	if err != nil {
 		jsonError(w, "Failed to add you to the group. Please try again later.", http.StatusInternalServerError)
 		return
@@ -839,7 +1040,7 @@ func CreateGroup(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(group)
 }
 
-func AddUsersToGroup(w http.ResponseWriter, r *http.Request) {
+func (s *Server) AddUsersToGroup(w http.ResponseWriter, r *http.Request) {
 	var groupUsers model.GroupUsers
 
 	vars := mux.Vars(r)
@@ -858,7 +1059,7 @@ func AddUsersToGroup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, userID := range groupUsers.UserIDs {
-		err := insertUserIntoGroup(groupID, userID)
+		err := s.groups.AddMember(int64(groupID), userID)
 		if err != nil {
 			jsonError(w, "Failed to add users to the group. Please try again later.", http.StatusInternalServerError)
 			return
@@ -873,176 +1074,806 @@ func AddUsersToGroup(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func GetGroupUsers(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
+func (s *Server) UpdateGroupRoundingPolicy(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	groupID, ok := vars["groupId"]
-	if !ok {
-		jsonError(w, "Group ID is required", http.StatusBadRequest)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
 		return
 	}
 
-	// Use direct string formatting instead of parameter binding
-	query := fmt.Sprintf("SELECT u.user_id, u.name, u.email FROM users u JOIN group_users gu ON u.user_id = gu.user_id WHERE gu.group_id = %s", groupID)
-
-	rows, err := db.Query(query)
-	if err != nil {
-		log.Printf("Error querying group users: %v", err)
-		jsonError(w, "Failed to fetch group members.", http.StatusInternalServerError)
+	var input struct {
+		RoundingPolicy string `json:"rounding_policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		jsonError(w, "Invalid input. Please check your information and try again.", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var users []model.UserResponse
-	for rows.Next() {
-		var u model.UserResponse
-		err := rows.Scan(&u.UserID, &u.Name, &u.Email)
-		if err != nil {
-			jsonError(w, "Failed to process group members.", http.StatusInternalServerError)
-			return
-		}
-		users = append(users, u)
+	policy := rounding.Policy(strings.ToUpper(strings.TrimSpace(input.RoundingPolicy)))
+	if !rounding.IsValid(policy) {
+		jsonError(w, "Rounding policy must be one of LARGEST_REMAINDER, ROUND_ROBIN or PAYER_ABSORBS.", http.StatusBadRequest)
+		return
 	}
 
-	// Return empty array if no users found
-	if users == nil {
-		users = []model.UserResponse{}
+	group, err := s.groups.SetRoundingPolicy(groupID, string(policy))
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Group not found.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error updating rounding policy: %v", err)
+		jsonError(w, "Failed to update the group. Please try again later.", http.StatusInternalServerError)
+		return
 	}
+	group.BaseCurrency = groupBaseCurrency(group)
 
-	json.NewEncoder(w).Encode(users)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
 }
 
-func GetNotGroupUsers(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateGroupBaseCurrency(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	groupID := vars["groupId"]
-
-	rows, err := db.Query(`SELECT u.user_id, u.name, u.email
-	FROM users u
-	WHERE u.user_id NOT IN (
-		SELECT user_id
-		FROM group_users
-		WHERE group_id = $1
-	)`, groupID)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
 	if err != nil {
-		jsonError(w, "Failed to fetch available users. Please try again later.", http.StatusInternalServerError)
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var users []model.UserResponse
-	for rows.Next() {
-		var u model.UserResponse
-		err := rows.Scan(&u.UserID, &u.Name, &u.Email)
-		if err != nil {
-			jsonError(w, "Failed to process available users. Please try again later.", http.StatusInternalServerError)
-			return
-		}
-		users = append(users, u)
+	var input struct {
+		BaseCurrency string `json:"base_currency"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		jsonError(w, "Invalid input. Please check your information and try again.", http.StatusBadRequest)
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
-}
 
-func AddExpense(w http.ResponseWriter, r *http.Request) {
-	var expense model.Expense
-	err := json.NewDecoder(r.Body).Decode(&expense)
+	baseCurrency, err := rates.NormalizeCode(input.BaseCurrency)
 	if err != nil {
-		jsonError(w, "Invalid expense data. Please check your information and try again.", http.StatusBadRequest)
+		jsonError(w, "Please enter a valid 3-letter currency code.", http.StatusBadRequest)
 		return
 	}
-	vars := mux.Vars(r)
-	groupID := vars["groupId"]
 
-	query := `INSERT INTO items (group_id, amount, paid_by, description) VALUES ($1, $2, $3, $4) RETURNING item_id`
-	err = db.QueryRow(query, groupID, expense.Amount, expense.PayerID, expense.Description).Scan(&expense.ExpenseID)
-	if err != nil {
-		jsonError(w, "Failed to create expense. Please try again later.", http.StatusInternalServerError)
+	group, err := s.groups.SetBaseCurrency(groupID, baseCurrency)
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Group not found.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error updating base currency: %v", err)
+		jsonError(w, "Failed to update the group. Please try again later.", http.StatusInternalServerError)
 		return
 	}
+	group.RoundingPolicy = string(groupRoundingPolicy(group))
 
-	err = calculateBalances(&expense)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
 
+func (s *Server) GetGroupCategories(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
 	if err != nil {
-		query = `DELETE FROM items WHERE item_id = $1`
-		_, newErr := db.Exec(query, expense.ExpenseID)
-		if newErr != nil {
-			jsonError(w, "An error occurred while processing your expense. Please try again later.", http.StatusInternalServerError)
-			return
-		}
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		return
+	}
 
-		if strings.Contains(err.Error(), "sum of shares is not equal to the amount") {
-			jsonError(w, "The sum of individual shares must equal the total amount.", http.StatusInternalServerError)
-		} else if strings.Contains(err.Error(), "sum of shares is not equal to 100") {
-			jsonError(w, "When splitting by percentage, all percentages must add up to 100%.", http.StatusInternalServerError)
-		} else {
-			jsonError(w, "Failed to calculate balances. Please check your expense details and try again.", http.StatusInternalServerError)
-		}
+	categories, err := s.groupCategories(groupID)
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+		jsonError(w, "Failed to fetch categories. Please try again later.", http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(expense)
+	json.NewEncoder(w).Encode(categories)
 }
 
-func GetItemsByGroupId(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// AddGroupCategory adds a custom expense category to a group
+func (s *Server) AddGroupCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		jsonError(w, "Invalid input. Please check your information and try again.", http.StatusBadRequest)
+		return
+	}
+
+	name := normalizeCategory(input.Name)
+	if name == "" || len(name) > 32 {
+		jsonError(w, "Category names must be between 1 and 32 characters.", http.StatusBadRequest)
+		return
+	}
+	if name == balance.Uncategorized {
+		jsonError(w, "This category already exists.", http.StatusConflict)
+		return
+	}
+	for _, builtin := range builtinCategories {
+		if name == builtin {
+			jsonError(w, "This category already exists.", http.StatusConflict)
+			return
+		}
+	}
+
+	err = s.groups.AddCategory(groupID, name)
+	if errors.Is(err, repository.ErrDuplicate) {
+		jsonError(w, "This category already exists.", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error adding category: %v", err)
+		jsonError(w, "Failed to add category. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(model.Category{Name: name, Custom: true})
+}
+
+// GetCategoryReport totals a group's spending per category and member. The
+// optional from and to query parameters are inclusive YYYY-MM-DD dates.
+func (s *Server) GetCategoryReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var from, to time.Time
+	if value := query.Get("from"); value != "" {
+		if from, err = time.Parse(time.DateOnly, value); err != nil {
+			jsonError(w, "Please give dates as YYYY-MM-DD.", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if to, err = time.Parse(time.DateOnly, value); err != nil {
+			jsonError(w, "Please give dates as YYYY-MM-DD.", http.StatusBadRequest)
+			return
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		jsonError(w, "The end date must not be before the start date.", http.StatusBadRequest)
+		return
+	}
+
+	// to is inclusive, so the range runs until the start of the next day
+	end := to
+	if !end.IsZero() {
+		end = end.AddDate(0, 0, 1)
+	}
+	report, err := s.balances.Categories(groupID, from, end)
+	if err != nil {
+		log.Printf("Error building category report: %v", err)
+		jsonError(w, "Failed to build the report. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	report.From = query.Get("from")
+	report.To = query.Get("to")
+
+	members, err := s.groups.Members(groupID)
+	if err != nil {
+		log.Printf("Error fetching group members: %v", err)
+		jsonError(w, "Failed to build the report. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	names := make(map[int64]string, len(members))
+	for _, member := range members {
+		names[member.UserID] = member.Name
+	}
+	for i := range report.Members {
+		report.Members[i].Name = names[report.Members[i].UserID]
+	}
+	for _, category := range report.Categories {
+		for i := range category.Members {
+			category.Members[i].Name = names[category.Members[i].UserID]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (s *Server) GetGroupUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID.", http.StatusBadRequest)
+		return
+	}
+
+	users, err := s.groups.Members(groupID)
+	if err != nil {
+		log.Printf("Error querying group users: %v", err)
+		jsonError(w, "Failed to fetch group members.", http.StatusInternalServerError)
+		return
+	}
+
+	// Return empty array if no users found
+	if users == nil {
+		users = []model.UserResponse{}
+	}
+
+	json.NewEncoder(w).Encode(users)
+}
+
+func (s *Server) GetNotGroupUsers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID.", http.StatusBadRequest)
+		return
+	}
+
+	users, err := s.users.ListNotInGroup(groupID)
+	if err != nil {
+		jsonError(w, "Failed to fetch available users. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+func (s *Server) AddExpense(w http.ResponseWriter, r *http.Request) {
+	var expense model.Expense
+	err := json.NewDecoder(r.Body).Decode(&expense)
+	if err != nil {
+		jsonError(w, "Invalid expense data. Please check your information and try again.", http.StatusBadRequest)
+		return
+	}
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	group, err := s.groups.Get(groupID)
+	if err != nil {
+		log.Printf("Error fetching group: %v", err)
+		jsonError(w, "Failed to create expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	expense.RoundingPolicy = string(groupRoundingPolicy(group))
+
+	if err := s.resolveCategory(groupID, &expense.Category); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := normalizePayers(&expense); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := s.checkExpenseMembers(groupID, &expense); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	// The exchange rate is pinned as of the day the expense happened
+	expenseDate, err := resolveExpenseDate(&expense, time.Now().UTC())
+	if err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := s.resolveExpenseCurrency(group, &expense, expenseDate); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := s.items.Create(groupID, &expense, calculateBalances); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expense)
+}
+
+// maxRecurringCatchUp bounds how many missed occurrences of one recurring
+// expense are posted in a single scheduler run
+const maxRecurringCatchUp = 100
+
+var errInvalidOccurrence = errors.New("occurrence can't be split")
+
+// occurrenceCantBePosted reports whether posting an occurrence failed because
+// of the recurring expense itself, e.g. a missing exchange rate or a share
+// for someone who left the group, so retrying on the next run won't help
+func occurrenceCantBePosted(err error) bool {
+	return errors.Is(err, errInvalidOccurrence) || errors.Is(err, rates.ErrRateNotFound) || errors.Is(err, errNotGroupMember)
+}
+
+// recurringExpense builds the expense posted for an occurrence of a recurring
+// expense, pinning the exchange rate in effect when it was due
+func (s *Server) recurringExpense(group *model.Group, recurring model.RecurringExpense, scheduledFor time.Time) (model.Expense, error) {
+	expense := model.Expense{
+		GroupID:        recurring.GroupID,
+		Amount:         recurring.Amount,
+		PayerID:        recurring.PayerID,
+		Payers:         append([]model.ExpensePayer(nil), recurring.Payers...),
+		Description:    recurring.Description,
+		Category:       recurring.Category,
+		ExpenseDate:    scheduledFor.UTC().Format(time.DateOnly),
+		ExpenseType:    recurring.ExpenseType,
+		Shares:         append([]model.UserShare(nil), recurring.Shares...),
+		Currency:       recurring.Currency,
+		RoundingPolicy: string(groupRoundingPolicy(group)),
+	}
+
+	if err := normalizePayers(&expense); err != nil {
+		return expense, err
+	}
+	if err := s.resolveExpenseCurrency(group, &expense, scheduledFor); err != nil {
+		return expense, err
+	}
+	return expense, nil
+}
+
+func (s *Server) CreateRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	var recurring model.RecurringExpense
+	if err := json.NewDecoder(r.Body).Decode(&recurring); err != nil {
+		jsonError(w, "Invalid expense data. Please check your information and try again.", http.StatusBadRequest)
+		return
+	}
+	recurring.GroupID = groupID
+	recurring.CreatedBy = sessionUser(r).UserID
+	recurring.Active = true
+	now := time.Now()
+	if recurring.StartAt.IsZero() {
+		recurring.StartAt = now
+	}
+	// Past occurrences would all be posted at once as backdated expenses; a
+	// minute's grace allows for the client's clock running behind
+	if recurring.StartAt.Before(now.Add(-time.Minute)) {
+		jsonError(w, "The start date can't be in the past.", http.StatusBadRequest)
+		return
+	}
+	recurring.StartAt = recurring.StartAt.UTC()
+
+	sched, err := schedule.Parse(recurring.Cadence, recurring.StartAt)
+	if err != nil {
+		jsonError(w, "Please choose daily, weekly, monthly or a valid cron expression as the cadence.", http.StatusBadRequest)
+		return
+	}
+	recurring.NextRunAt = schedule.First(sched, recurring.StartAt)
+
+	group, err := s.groups.Get(groupID)
+	if err != nil {
+		log.Printf("Error fetching group: %v", err)
+		jsonError(w, "Failed to create recurring expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Split the first occurrence now so a definition that could never be
+	// posted is rejected up front
+	err = s.resolveCategory(groupID, &recurring.Category)
+	var expense model.Expense
+	if err == nil {
+		expense, err = s.recurringExpense(group, recurring, recurring.NextRunAt)
+	}
+	if err == nil {
+		err = s.checkExpenseMembers(groupID, &expense)
+	}
+	if err == nil {
+		err = calculateBalances(&expense)
+	}
+	if err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+	recurring.Currency = expense.Currency
+	recurring.Payers = expense.Payers
+	recurring.PayerID = expense.PayerID
+
+	if err := s.recurring.Create(&recurring); err != nil {
+		log.Printf("Error creating recurring expense: %v", err)
+		jsonError(w, "Failed to create recurring expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurring)
+}
+
+func (s *Server) GetRecurringExpenses(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	recurring, err := s.recurring.ListByGroup(groupID)
+	if err != nil {
+		log.Printf("Error fetching recurring expenses: %v", err)
+		jsonError(w, "Failed to fetch recurring expenses. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	if recurring == nil {
+		recurring = []model.RecurringExpense{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recurring)
+}
+
+// StopRecurringExpense stops future occurrences; expenses already posted stay
+func (s *Server) StopRecurringExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	recurringID, err := strconv.ParseInt(vars["recurringExpenseId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid recurring expense ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	err = s.recurring.Deactivate(recurringID)
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Recurring expense not found.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error stopping recurring expense: %v", err)
+		jsonError(w, "Failed to stop recurring expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":              "Recurring expense stopped successfully",
+		"recurring_expense_id": recurringID,
+	})
+}
+
+// MaterializeRecurringExpenses posts every occurrence of a recurring expense
+// that has fallen due by now, including ones missed while the server was
+// down. Each occurrence is posted at most once, so running it again or from
+// several instances is safe.
+func (s *Server) MaterializeRecurringExpenses(now time.Time) {
+	due, err := s.recurring.Due(now)
+	if err != nil {
+		log.Printf("Error fetching due recurring expenses: %v", err)
+		return
+	}
+
+	for _, recurring := range due {
+		if err := s.materializeRecurringExpense(recurring, now); err != nil {
+			log.Printf("Error posting recurring expense %d: %v", recurring.ID, err)
+		}
+	}
+}
+
+func (s *Server) materializeRecurringExpense(recurring model.RecurringExpense, now time.Time) error {
+	sched, err := schedule.Parse(recurring.Cadence, recurring.StartAt)
+	if err != nil {
+		return err
+	}
+	group, err := s.groups.Get(recurring.GroupID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch group: %w", err)
+	}
+
+	split := func(expense *model.Expense) error {
+		if err := calculateBalances(expense); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidOccurrence, err)
+		}
+		return nil
+	}
+
+	scheduledFor := recurring.NextRunAt
+	for i := 0; i < maxRecurringCatchUp && !scheduledFor.After(now); i++ {
+		expense, err := s.recurringExpense(group, recurring, scheduledFor)
+		if err == nil {
+			err = s.checkExpenseMembers(recurring.GroupID, &expense)
+		}
+
+		next := sched.Next(scheduledFor)
+		var posted bool
+		if err == nil {
+			posted, err = s.recurring.Materialize(recurring.ID, scheduledFor, next, &expense, split)
+		}
+		if occurrenceCantBePosted(err) {
+			reason := fmt.Sprintf("The occurrence due %s couldn't be posted: %v", scheduledFor.Format(time.DateOnly), err)
+			if failErr := s.recurring.Fail(recurring.ID, reason); failErr != nil {
+				return failErr
+			}
+			return fmt.Errorf("stopped recurring expense: %w", err)
+		} else if err != nil {
+			return err
+		}
+		if posted {
+			log.Printf("Posted recurring expense %d due %s as item %d", recurring.ID, scheduledFor.Format(time.RFC3339), expense.ExpenseID)
+		}
+
+		if next.IsZero() {
+			return s.recurring.Deactivate(recurring.ID)
+		}
+		scheduledFor = next
+	}
+	return nil
+}
+
+func (s *Server) UpdateExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	expenseID, err := strconv.ParseInt(vars["expenseId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid expense ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	var expense model.Expense
+	if err := json.NewDecoder(r.Body).Decode(&expense); err != nil {
+		jsonError(w, "Invalid expense data. Please check your information and try again.", http.StatusBadRequest)
+		return
+	}
+	expense.ExpenseID = expenseID
+
+	existing, err := s.items.Get(expense.ExpenseID)
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Expense not found or already deleted.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching expense: %v", err)
+		jsonError(w, "Failed to update expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	group, err := s.groups.Get(existing.GroupID)
+	if err != nil {
+		log.Printf("Error fetching group: %v", err)
+		jsonError(w, "Failed to update expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+	expense.RoundingPolicy = string(groupRoundingPolicy(group))
+
+	if err := s.resolveCategory(existing.GroupID, &expense.Category); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := normalizePayers(&expense); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := s.checkExpenseMembers(existing.GroupID, &expense); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	// Leaving the date out keeps the existing one
+	if strings.TrimSpace(expense.ExpenseDate) == "" {
+		expense.ExpenseDate = existing.ExpenseDate
+	}
+	expenseDate, err := resolveExpenseDate(&expense, time.Now().UTC())
+	if err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	if err := s.resolveExpenseCurrency(group, &expense, expenseDate); err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	err = s.items.Update(&expense, calculateBalances)
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Expense not found or already deleted.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeExpenseError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expense)
+}
+
+func (s *Server) DeleteExpense(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	expenseID, err := strconv.ParseInt(vars["expenseId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid expense ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	err = s.items.Delete(expenseID)
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Expense not found or already deleted.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting expense: %v", err)
+		jsonError(w, "Failed to delete expense. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Expense deleted successfully",
+		"expense_id": expenseID,
+	})
+}
+
+// Page sizes for listing a group's expenses and transactions
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// encodeItemCursor turns the position of the last expense on a page into an
+// opaque cursor
+func encodeItemCursor(expense model.Expense) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s,%d", expense.ExpenseDate, expense.ExpenseID)))
+}
+
+func decodeItemCursor(cursor string) (*repository.ItemCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	date, id, ok := strings.Cut(string(raw), ",")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor %q", raw)
+	}
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return nil, err
+	}
+	itemID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.ItemCursor{ExpenseDate: date, ItemID: itemID}, nil
+}
+
+// parseItemFilter reads the filters, search and page of a group's expense
+// listing from the query string, returning a message for the caller on error
+func parseItemFilter(query url.Values) (repository.ItemFilter, string) {
+	filter := repository.ItemFilter{Limit: defaultPageSize, Search: query.Get("q")}
+
+	ids := map[string]*int64{"payer_id": &filter.PayerID, "participant_id": &filter.ParticipantID}
+	for name, target := range ids {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, "Invalid user ID. Please try again."
+			}
+			*target = id
+		}
+	}
+
+	amounts := map[string]*int64{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount}
+	for name, target := range amounts {
+		if value := query.Get(name); value != "" {
+			amount, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || amount < 0 {
+				return filter, "Amounts must be whole numbers of at least zero."
+			}
+			*target = amount
+		}
+	}
+	if filter.MaxAmount != 0 && filter.MinAmount > filter.MaxAmount {
+		return filter, "The minimum amount must not be more than the maximum amount."
+	}
+
+	for name, target := range map[string]*string{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			if _, err := time.Parse(time.DateOnly, value); err != nil {
+				return filter, "Please give dates as YYYY-MM-DD."
+			}
+			*target = value
+		}
+	}
+	if filter.From != "" && filter.To != "" && filter.To < filter.From {
+		return filter, "The end date must not be before the start date."
+	}
+
+	if query.Has("category") {
+		category := normalizeCategory(query.Get("category"))
+		if category == balance.Uncategorized {
+			category = ""
+		}
+		filter.Category = &category
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return filter, fmt.Sprintf("The page size must be between 1 and %d.", maxPageSize)
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeItemCursor(value)
+		if err != nil {
+			return filter, "Invalid cursor. Please start again from the first page."
+		}
+		filter.After = cursor
+	}
+
+	return filter, ""
+}
+
+// GetItemsByGroupId returns a page of a group's expenses, latest first, with
+// their shares and payers. Query parameters filter by payer_id,
+// participant_id, from and to dates, min_amount and max_amount, category and
+// words in the description (q); limit and cursor page through the results.
+func (s *Server) GetItemsByGroupId(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
-	groupID := vars["groupId"]
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID.", http.StatusBadRequest)
+		return
+	}
 
-	// Use direct string formatting
-	query := fmt.Sprintf("SELECT item_id, amount, paid_by, description, created_at FROM items WHERE group_id = %s ORDER BY created_at DESC", groupID)
+	filter, message := parseItemFilter(r.URL.Query())
+	if message != "" {
+		jsonError(w, message, http.StatusBadRequest)
+		return
+	}
 
-	rows, err := db.Query(query)
+	// One extra item tells whether there is another page
+	pageSize := filter.Limit
+	filter.Limit++
+	items, err := s.items.ListByGroup(groupID, filter)
 	if err != nil {
 		log.Printf("Error querying items: %v", err)
 		jsonError(w, "Failed to fetch expenses.", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	var items []model.Expense
-	for rows.Next() {
-		var item model.Expense
-		err := rows.Scan(&item.ExpenseID, &item.Amount, &item.PayerID, &item.Description, &item.Created_at)
+	page := model.ExpensePage{Items: items}
+	if len(items) > pageSize {
+		page.Items = items[:pageSize]
+		page.NextCursor = encodeItemCursor(page.Items[pageSize-1])
+	}
+
+	for i := range page.Items {
+		page.Items[i].Shares, err = s.splits.ListByItem(page.Items[i].ExpenseID)
 		if err != nil {
-			jsonError(w, "Failed to process expenses.", http.StatusInternalServerError)
+			jsonError(w, "Failed to fetch expense details.", http.StatusInternalServerError)
 			return
 		}
 
-		// Get shares with direct string formatting
-		sharesQuery := fmt.Sprintf("SELECT user_id, share FROM item_splits WHERE item_id = %d", item.ExpenseID)
-		shareRows, err := db.Query(sharesQuery)
+		page.Items[i].Payers, err = s.splits.Payers(page.Items[i].ExpenseID)
 		if err != nil {
 			jsonError(w, "Failed to fetch expense details.", http.StatusInternalServerError)
 			return
 		}
 
-		for shareRows.Next() {
-			var userShare model.UserShare
-			err := shareRows.Scan(&userShare.UserID, &userShare.ShareAmount)
-			if err != nil {
-				shareRows.Close()
-				jsonError(w, "Failed to process expense shares.", http.StatusInternalServerError)
-				return
-			}
-			item.Shares = append(item.Shares, userShare)
+		// Items recorded before multiple payers were supported have none stored
+		if page.Items[i].Payers == nil {
+			page.Items[i].Payers = []model.ExpensePayer{{UserID: page.Items[i].PayerID, Amount: page.Items[i].Amount}}
 		}
-		shareRows.Close()
-
-		items = append(items, item)
 	}
 
 	// Return empty array if no items found
-	if items == nil {
-		items = []model.Expense{}
+	if page.Items == nil {
+		page.Items = []model.Expense{}
 	}
 
-	json.NewEncoder(w).Encode(items)
+	json.NewEncoder(w).Encode(page)
 }
 
-func GetSettlements(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetSettlements(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
@@ -1085,154 +1916,198 @@ func GetSettlements(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	balances, baseCurrency, err := s.balances.ForUser(int64(groupID), int64(userID), requestData.Users)
+	if err != nil {
+		log.Printf("Error calculating group balances: %v", err)
+		jsonError(w, "Failed to calculate settlements.", http.StatusInternalServerError)
+		return
+	}
+
 	var settlements []map[string]interface{}
 
-	for _, otherUserID := range requestData.Users {
-		// Skip self
-		if otherUserID == int64(userID) {
-			continue
+	for _, userBalance := range balances {
+		settlement := map[string]interface{}{
+			"user_id":      userBalance.UserID,
+			"share_amount": userBalance.ShareAmount,
+			"currency":     baseCurrency,
 		}
+		settlements = append(settlements, settlement)
+	}
 
-		// Calculate settlement using direct queries
-		itemsOtherUserPaid := fmt.Sprintf(
-			"SELECT item_id FROM items WHERE group_id = %d AND paid_by = %d",
-			groupID, otherUserID)
+	// Return empty array if no settlements
+	if settlements == nil {
+		settlements = []map[string]interface{}{}
+	}
 
-		var totalAmount int64 = 0
+	json.NewEncoder(w).Encode(settlements)
+}
 
-		// Process items paid by other user
-		itemRows, err := db.Query(itemsOtherUserPaid)
-		if err != nil {
-			log.Printf("Error fetching items paid by other user: %v", err)
-			continue
-		}
+func (s *Server) GetSettlePlan(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID.", http.StatusBadRequest)
+		return
+	}
 
-		for itemRows.Next() {
-			var itemID int64
-			err := itemRows.Scan(&itemID)
-			if err != nil {
-				log.Printf("Error scanning item ID: %v", err)
-				continue
-			}
+	nets, baseCurrency, err := s.balances.ForGroup(groupID)
+	if err != nil {
+		log.Printf("Error calculating net balances: %v", err)
+		jsonError(w, "Failed to calculate the settle-up plan.", http.StatusInternalServerError)
+		return
+	}
 
-			// Get user's share of this item
-			var shareAmount int64
-			shareQuery := fmt.Sprintf(
-				"SELECT share FROM item_splits WHERE item_id = %d AND user_id = %d",
-				itemID, userID)
-
-			err = db.QueryRow(shareQuery).Scan(&shareAmount)
-			if err == sql.ErrNoRows {
-				continue
-			} else if err != nil {
-				log.Printf("Error getting share: %v", err)
-				continue
-			}
+	plan := model.SettlePlan{
+		GroupID:   groupID,
+		Currency:  baseCurrency,
+		Balances:  []model.UserShare{},
+		Transfers: balance.Simplify(nets),
+	}
+	for userID, net := range nets {
+		plan.Balances = append(plan.Balances, model.UserShare{UserID: userID, ShareAmount: net})
+	}
+	sort.Slice(plan.Balances, func(a, b int) bool {
+		return plan.Balances[a].UserID < plan.Balances[b].UserID
+	})
+	for i := range plan.Transfers {
+		plan.Transfers[i].Currency = baseCurrency
+	}
 
-			totalAmount += shareAmount
-		}
-		itemRows.Close()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
 
-		// Process transactions already made
-		transQuery := fmt.Sprintf(
-			"SELECT amount FROM transactions WHERE group_id = %d AND user_id = %d AND payer_id = %d",
-			groupID, userID, otherUserID)
+// groupLocks holds a mutex per group so a balance can be checked and a
+// payment recorded against it without another request slipping in between
+type groupLocks struct {
+	mu    sync.Mutex
+	locks map[int64]*sync.Mutex
+}
 
-		transRows, err := db.Query(transQuery)
-		if err != nil {
-			log.Printf("Error fetching transactions: %v", err)
-			continue
-		}
+// lock locks the group and returns the function that unlocks it
+func (g *groupLocks) lock(groupID int64) func() {
+	g.mu.Lock()
+	if g.locks == nil {
+		g.locks = make(map[int64]*sync.Mutex)
+	}
+	l, ok := g.locks[groupID]
+	if !ok {
+		l = &sync.Mutex{}
+		g.locks[groupID] = l
+	}
+	g.mu.Unlock()
 
-		for transRows.Next() {
-			var amount int64
-			err := transRows.Scan(&amount)
-			if err != nil {
-				log.Printf("Error scanning transaction amount: %v", err)
-				continue
-			}
-			totalAmount -= amount
-		}
-		transRows.Close()
+	l.Lock()
+	return l.Unlock
+}
+
+// SettleUp records a payment the session user made or received after checking
+// it against what the payer owes the receiver, so payments can't be recorded
+// in the wrong direction or overpay unless that is explicitly allowed
+func (s *Server) SettleUp(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid group ID.", http.StatusBadRequest)
+		return
+	}
 
-		// Items paid by current user
-		itemsUserPaid := fmt.Sprintf(
-			"SELECT item_id FROM items WHERE group_id = %d AND paid_by = %d",
-			groupID, userID)
+	var request model.SettleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		jsonError(w, "Invalid payment data. Please check your information and try again.", http.StatusBadRequest)
+		return
+	}
 
-		itemRows, err = db.Query(itemsUserPaid)
+	if request.PayerID == request.ReceiverID {
+		jsonError(w, "The payer and the receiver must be different people.", http.StatusBadRequest)
+		return
+	}
+	if userID := sessionUser(r).UserID; userID != request.PayerID && userID != request.ReceiverID {
+		jsonError(w, "Only the payer or the receiver can record this payment.", http.StatusForbidden)
+		return
+	}
+	for _, userID := range []int64{request.PayerID, request.ReceiverID} {
+		isMember, err := s.groups.IsMember(groupID, userID)
 		if err != nil {
-			log.Printf("Error fetching items paid by user: %v", err)
-			continue
+			jsonError(w, "Failed to verify group members. Please try again later.", http.StatusInternalServerError)
+			return
 		}
-
-		for itemRows.Next() {
-			var itemID int64
-			err := itemRows.Scan(&itemID)
-			if err != nil {
-				log.Printf("Error scanning item ID: %v", err)
-				continue
-			}
-
-			// Get other user's share of this item
-			var shareAmount int64
-			shareQuery := fmt.Sprintf(
-				"SELECT share FROM item_splits WHERE item_id = %d AND user_id = %d",
-				itemID, otherUserID)
-
-			err = db.QueryRow(shareQuery).Scan(&shareAmount)
-			if err == sql.ErrNoRows {
-				continue
-			} else if err != nil {
-				log.Printf("Error getting share: %v", err)
-				continue
-			}
-
-			totalAmount -= shareAmount
+		if !isMember {
+			jsonError(w, "Both people must be members of this group.", http.StatusBadRequest)
+			return
 		}
-		itemRows.Close()
+	}
 
-		// More transactions
-		transQuery = fmt.Sprintf(
-			"SELECT amount FROM transactions WHERE group_id = %d AND user_id = %d AND payer_id = %d",
-			groupID, otherUserID, userID)
+	// Hold the group until the payment is recorded so two payments can't both
+	// be checked against the same balance and overpay it together
+	unlock := s.settling.lock(groupID)
+	defer unlock()
 
-		transRows, err = db.Query(transQuery)
-		if err != nil {
-			log.Printf("Error fetching transactions: %v", err)
-			continue
-		}
+	owed, err := s.balances.Pair(groupID, request.ReceiverID, request.PayerID)
+	if err != nil {
+		log.Printf("Error calculating balance: %v", err)
+		jsonError(w, "Failed to calculate the balance. Please try again later.", http.StatusInternalServerError)
+		return
+	}
 
-		for transRows.Next() {
-			var amount int64
-			err := transRows.Scan(&amount)
-			if err != nil {
-				log.Printf("Error scanning transaction amount: %v", err)
-				continue
-			}
-			totalAmount += amount
+	if request.SettleInFull {
+		if request.Amount != 0 && request.Amount != owed {
+			jsonError(w, "Leave out the amount when settling in full.", http.StatusBadRequest)
+			return
 		}
-		transRows.Close()
+		request.Amount = owed
+	}
 
-		// Add to settlements if non-zero
-		if totalAmount != 0 {
-			settlement := map[string]interface{}{
-				"user_id":      otherUserID,
-				"share_amount": totalAmount,
-			}
-			settlements = append(settlements, settlement)
-		}
+	switch {
+	case owed < 0:
+		jsonError(w, fmt.Sprintf("User %d doesn't owe user %d anything; user %d owes user %d %d. Check the direction of the payment.",
+			request.PayerID, request.ReceiverID, request.ReceiverID, request.PayerID, -owed), http.StatusBadRequest)
+		return
+	case request.Amount <= 0 && owed == 0:
+		jsonError(w, "These two people are already settled up.", http.StatusBadRequest)
+		return
+	case request.Amount <= 0:
+		jsonError(w, "Payment amount must be greater than zero.", http.StatusBadRequest)
+		return
+	case request.Amount > owed && !request.AllowOverpayment:
+		jsonError(w, fmt.Sprintf("Payment of %d is more than the %d owed. Set allow_overpayment to record it anyway.",
+			request.Amount, owed), http.StatusBadRequest)
+		return
 	}
 
-	// Return empty array if no settlements
-	if settlements == nil {
-		settlements = []map[string]interface{}{}
+	transaction := model.Transactions{
+		UserID:  request.ReceiverID,
+		PayerID: request.PayerID,
+		GroupID: groupID,
+		Amount:  request.Amount,
+	}
+	if err := s.transactions.Create(&transaction); err != nil {
+		jsonError(w, "Failed to record payment. Please try again later.", http.StatusInternalServerError)
+		return
 	}
 
-	json.NewEncoder(w).Encode(settlements)
+	owed, err = s.balances.Pair(groupID, request.ReceiverID, request.PayerID)
+	if err != nil {
+		log.Printf("Error calculating balance: %v", err)
+		jsonError(w, "Payment recorded, but the updated balance couldn't be calculated.", http.StatusInternalServerError)
+		return
+	}
+	baseCurrency, err := s.balances.BaseCurrency(groupID)
+	if err != nil {
+		log.Printf("Error fetching group currency: %v", err)
+		jsonError(w, "Payment recorded, but the updated balance couldn't be calculated.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.SettleResponse{
+		Transaction: transaction,
+		Balance:     owed,
+		Currency:    baseCurrency,
+	})
 }
 
-func GetMemoriesHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetMemoriesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
@@ -1242,41 +2117,12 @@ func GetMemoriesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := db.Query(`
-		SELECT id, group_id, filename, image_url, created_at 
-		FROM memories 
-		WHERE group_id = $1 
-		ORDER BY created_at DESC`, groupId)
+	memories, err := s.memories.ListByGroup(int64(groupId))
 	if err != nil {
 		log.Printf("Error querying memories: %v", err)
 		jsonError(w, "Failed to fetch memories. Please try again later.", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var memories []model.Memory
-	for rows.Next() {
-		var memory model.Memory
-		err := rows.Scan(
-			&memory.ID,
-			&memory.GroupID,
-			&memory.Filename,
-			&memory.ImageURL,
-			&memory.CreatedAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning memory row: %v", err)
-			continue
-		}
-
-		memories = append(memories, memory)
-	}
-
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating memory rows: %v", err)
-		jsonError(w, "Error fetching memories. Please try again later.", http.StatusInternalServerError)
-		return
-	}
 
 	response := model.MemoryResponse{
 		Success:  true,
@@ -1285,7 +2131,7 @@ func GetMemoriesHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func UploadMemoryHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UploadMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	r2Storage, err := cloudfareR2.NewR2Storage(
@@ -1315,6 +2161,10 @@ func UploadMemoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.requireGroupMember(w, r, int64(groupId)) {
+		return
+	}
+
 	file, fileHeader, err := r.FormFile("image")
 	if err != nil {
 		jsonError(w, "Error retrieving file. Please try again.", http.StatusBadRequest)
@@ -1336,18 +2186,7 @@ func UploadMemoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var memory model.Memory
-	err = db.QueryRow(`
-		INSERT INTO memories (group_id, filename, image_url, created_at)
-		VALUES ($1, $2, $3, NOW())
-		RETURNING id, group_id, filename, image_url, created_at`,
-		groupId, filename, imageURL).Scan(
-		&memory.ID,
-		&memory.GroupID,
-		&memory.Filename,
-		&memory.ImageURL,
-		&memory.CreatedAt,
-	)
+	memory, err := s.memories.Create(int64(groupId), filename, imageURL)
 	if err != nil {
 		log.Printf("Error inserting memory: %v", err)
 		// Try to delete the file from R2 since the DB insert failed
@@ -1361,169 +2200,397 @@ func UploadMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	response := model.MemoryResponse{
 		Success: true,
 		Message: "Memory uploaded successfully",
-		Memory:  &memory,
+		Memory:  memory,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
-func DeleteMemoryHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) DeleteMemoryHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	r2Storage, err := cloudfareR2.NewR2Storage(
-		os.Getenv("R2_ACCESS_KEY"),
-		os.Getenv("R2_SECRET_KEY"),
-		os.Getenv("R2_ACCOUNT_ID"),
-		os.Getenv("R2_BUCKET_NAME"),
-	)
-	if err != nil {
-		log.Fatalf("Failed to initialize R2 storage: %v", err)
+	r2Storage, err := cloudfareR2.NewR2Storage(
+		os.Getenv("R2_ACCESS_KEY"),
+		os.Getenv("R2_SECRET_KEY"),
+		os.Getenv("R2_ACCOUNT_ID"),
+		os.Getenv("R2_BUCKET_NAME"),
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize R2 storage: %v", err)
+	}
+
+	vars := mux.Vars(r)
+	memoryId, err := strconv.Atoi(vars["memoryId"])
+	if err != nil {
+		jsonError(w, "Invalid memory ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+
+	memory, err := s.memories.Get(int64(memoryId))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			jsonError(w, "Memory not found or already deleted.", http.StatusNotFound)
+		} else {
+			log.Printf("Error fetching memory: %v", err)
+			jsonError(w, "Failed to retrieve memory information. Please try again later.", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	err = s.memories.Delete(int64(memoryId))
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Memory not found or already deleted.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error deleting memory from database: %v", err)
+		jsonError(w, "Failed to delete memory. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	// Delete the file from R2
+	if err := r2Storage.DeleteFile(memory.Filename); err != nil {
+		log.Printf("Warning: Could not delete file from R2: %v", err)
+		// Continue anyway, as the database record is already deleted
+	}
+
+	response := model.MemoryResponse{
+		Success: true,
+		Message: "Memory deleted successfully",
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func isValidImageType(contentType string) bool {
+	validTypes := map[string]bool{
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/gif":  true,
+		"image/webp": true,
+		"image/bmp":  true,
+		"image/tiff": true,
+	}
+	return validTypes[contentType]
+}
+
+// encodeTransactionCursor turns the last transaction on a page into an opaque
+// cursor
+func encodeTransactionCursor(transaction model.Transactions) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(transaction.ID, 10)))
+}
+
+func decodeTransactionCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("malformed cursor %q", raw)
+	}
+	return id, nil
+}
+
+// parseTransactionFilter reads the filters and page of a group's transaction
+// listing from the query string, returning a message for the caller on error
+func parseTransactionFilter(query url.Values) (repository.TransactionFilter, string) {
+	filter := repository.TransactionFilter{Limit: defaultPageSize}
+
+	ids := map[string]*int64{"payer_id": &filter.PayerID, "receiver_id": &filter.ReceiverID}
+	for name, target := range ids {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return filter, "Invalid user ID. Please try again."
+			}
+			*target = id
+		}
+	}
+
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			date, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return filter, "Please give dates as YYYY-MM-DD."
+			}
+			*target = date
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, "The end date must not be before the start date."
+	}
+	// to is inclusive, so the range runs until the start of the next day
+	if !filter.To.IsZero() {
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return filter, fmt.Sprintf("The page size must be between 1 and %d.", maxPageSize)
+		}
+		filter.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		id, err := decodeTransactionCursor(value)
+		if err != nil {
+			return filter, "Invalid cursor. Please start again from the first page."
+		}
+		filter.BeforeID = id
 	}
 
+	return filter, ""
+}
+
+// GetTransactions returns a page of a group's transactions, newest first,
+// including reversed ones. Query parameters filter by payer_id, receiver_id
+// and from and to dates; limit and cursor page through the results.
+func (s *Server) GetTransactions(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	memoryId, err := strconv.Atoi(vars["memoryId"])
+	groupID, err := strconv.ParseInt(vars["groupId"], 10, 64)
 	if err != nil {
-		jsonError(w, "Invalid memory ID. Please try again.", http.StatusBadRequest)
+		jsonError(w, "Invalid group ID.", http.StatusBadRequest)
 		return
 	}
 
-	var filename string
-	err = db.QueryRow("SELECT filename FROM memories WHERE id = $1", memoryId).Scan(&filename)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			jsonError(w, "Memory not found or already deleted.", http.StatusNotFound)
-		} else {
-			log.Printf("Error fetching memory: %v", err)
-			jsonError(w, "Failed to retrieve memory information. Please try again later.", http.StatusInternalServerError)
-		}
+	filter, message := parseTransactionFilter(r.URL.Query())
+	if message != "" {
+		jsonError(w, message, http.StatusBadRequest)
 		return
 	}
 
-	result, err := db.Exec("DELETE FROM memories WHERE id = $1", memoryId)
+	// One extra transaction tells whether there is another page
+	pageSize := filter.Limit
+	filter.Limit++
+	transactions, err := s.transactions.ListByGroup(groupID, filter)
 	if err != nil {
-		log.Printf("Error deleting memory from database: %v", err)
-		jsonError(w, "Failed to delete memory. Please try again later.", http.StatusInternalServerError)
+		log.Printf("Error querying transactions: %v", err)
+		jsonError(w, "Failed to fetch transactions. Please try again later.", http.StatusInternalServerError)
 		return
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		jsonError(w, "Memory not found or already deleted.", http.StatusNotFound)
+	baseCurrency, err := s.balances.BaseCurrency(groupID)
+	if err != nil {
+		log.Printf("Error fetching group currency: %v", err)
+		jsonError(w, "Failed to fetch transactions. Please try again later.", http.StatusInternalServerError)
 		return
 	}
 
-	// Delete the file from R2
-	if err := r2Storage.DeleteFile(filename); err != nil {
-		log.Printf("Warning: Could not delete file from R2: %v", err)
-		// Continue anyway, as the database record is already deleted
+	page := model.TransactionPage{Transactions: transactions, Currency: baseCurrency}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		page.NextCursor = encodeTransactionCursor(page.Transactions[pageSize-1])
 	}
 
-	response := model.MemoryResponse{
-		Success: true,
-		Message: "Memory deleted successfully",
+	// Always return an array (even if empty)
+	if page.Transactions == nil {
+		page.Transactions = []model.Transactions{}
 	}
-	json.NewEncoder(w).Encode(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
 }
 
-func isValidImageType(contentType string) bool {
-	validTypes := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
-		"image/gif":  true,
-		"image/webp": true,
-		"image/bmp":  true,
-		"image/tiff": true,
+// Reasons a transaction can't be reversed
+var (
+	errNotTransactionParty = errors.New("user is neither the payer nor the receiver")
+	errNettingNotBatched   = errors.New("netting transaction has no batch")
+)
+
+// reverseNetting reverses every leg of the netting a transaction belongs to,
+// since reversing one alone would leave the pair's balances out of step
+// across groups. It returns the given transaction as reversed.
+func (s *Server) reverseNetting(transaction *model.Transactions, userID int64) (*model.Transactions, error) {
+	if transaction.BatchID == 0 {
+		return nil, errNettingNotBatched
 	}
-	return validTypes[contentType]
+
+	reversed, err := s.transactions.ReverseBatch(transaction.BatchID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reversed {
+		if reversed[i].ID == transaction.ID {
+			return &reversed[i], nil
+		}
+	}
+	return nil, repository.ErrAlreadyReversed
 }
 
-func GetTransactions(w http.ResponseWriter, r *http.Request) {
+// ReverseTransaction undoes a recorded payment. The row is kept and marked as
+// reversed so there is a record of who undid it and when.
+func (s *Server) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	vars := mux.Vars(r)
-	groupIDStr, ok := vars["groupId"]
-	if !ok {
-		jsonError(w, "Group ID is required", http.StatusBadRequest)
+	transactionID, err := strconv.ParseInt(vars["transactionId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid transaction ID.", http.StatusBadRequest)
 		return
 	}
 
-	// Use string formatting to avoid prepared statements
-	query := fmt.Sprintf(`
-        SELECT id, user_id, payer_id, group_id, amount, created_at 
-        FROM transactions 
-        WHERE group_id = %s 
-        ORDER BY created_at DESC`, groupIDStr)
-
-	// Log the query for debugging
-	log.Printf("Executing query: %s", query)
+	transaction, err := s.transactions.Get(transactionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Transaction not found.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error fetching transaction: %v", err)
+		jsonError(w, "Failed to reverse transaction. Please try again later.", http.StatusInternalServerError)
+		return
+	}
 
-	rows, err := db.Query(query)
-	if err != nil {
-		log.Printf("Error querying transactions: %v", err)
-		// Return empty array on error
-		json.NewEncoder(w).Encode([]struct{}{})
+	userID := sessionUser(r).UserID
+	if userID != transaction.PayerID && userID != transaction.UserID {
+		err = errNotTransactionParty
+	} else if transaction.Kind == model.TransactionNetting {
+		transaction, err = s.reverseNetting(transaction, userID)
+	} else {
+		transaction, err = s.transactions.Reverse(transactionID, userID)
+	}
+	if errors.Is(err, errNotTransactionParty) {
+		jsonError(w, "Only the payer or the receiver can reverse this transaction.", http.StatusForbidden)
+		return
+	} else if errors.Is(err, errNettingNotBatched) {
+		jsonError(w, "This netting can't be reversed one transaction at a time.", http.StatusConflict)
+		return
+	} else if errors.Is(err, repository.ErrNotFound) {
+		jsonError(w, "Transaction not found.", http.StatusNotFound)
+		return
+	} else if errors.Is(err, repository.ErrAlreadyReversed) {
+		jsonError(w, "This transaction has already been reversed.", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error reversing transaction: %v", err)
+		jsonError(w, "Failed to reverse transaction. Please try again later.", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
-	var transactions []map[string]interface{}
+	json.NewEncoder(w).Encode(transaction)
+}
 
-	for rows.Next() {
-		var id, userID, payerID, groupID, amount int64
-		var createdAt time.Time
+// groupBalances returns the user's non-zero balance with each other member of
+// each of groups, in the groups' base currencies
+func (s *Server) groupBalances(userID int64, groups []model.Group) ([]model.Balance, error) {
+	names := make(map[int64]string)
+	balances := []model.Balance{}
 
-		err := rows.Scan(&id, &userID, &payerID, &groupID, &amount, &createdAt)
+	for _, group := range groups {
+		memberIDs, err := s.groups.MemberIDs(group.GroupID)
 		if err != nil {
-			log.Printf("Error scanning transaction row: %v", err)
+			return nil, fmt.Errorf("failed to fetch members of group %d: %w", group.GroupID, err)
+		}
+		if len(memberIDs) < 2 {
 			continue
 		}
 
-		transaction := map[string]interface{}{
-			"id":         id,
-			"user_id":    userID,
-			"payer_id":   payerID,
-			"group_id":   groupID,
-			"amount":     amount,
-			"created_at": createdAt,
+		settlements, baseCurrency, err := s.balances.ForUser(group.GroupID, userID, memberIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate balances in group %d: %w", group.GroupID, err)
 		}
 
-		transactions = append(transactions, transaction)
+		for _, settlement := range settlements {
+			name, ok := names[settlement.UserID]
+			if !ok {
+				otherUser, err := s.users.Get(settlement.UserID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to fetch user %d: %w", settlement.UserID, err)
+				}
+				name = otherUser.Name
+				names[settlement.UserID] = name
+			}
+
+			balances = append(balances, model.Balance{
+				OtherUserID:   settlement.UserID,
+				OtherUserName: name,
+				Amount:        settlement.ShareAmount,
+				Currency:      baseCurrency,
+				GroupID:       group.GroupID,
+				GroupName:     group.GroupName,
+			})
+		}
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Error after scanning rows: %v", err)
+	return balances, nil
+}
+
+// GetUserBalances returns what the user owes or is owed by each other person
+// across every group they share, with the per-group breakdown
+func (s *Server) GetUserBalances(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.ParseInt(vars["userId"], 10, 64)
+	if err != nil {
+		jsonError(w, "Invalid user ID. Please try again.", http.StatusBadRequest)
+		return
 	}
 
-	// Always return an array (even if empty)
-	if transactions == nil {
-		transactions = []map[string]interface{}{}
+	groups, err := s.fetchAllGroupsByUserID(userID)
+	if err != nil {
+		jsonError(w, "Failed to fetch your groups. Please try again later.", http.StatusInternalServerError)
+		return
 	}
 
-	json.NewEncoder(w).Encode(transactions)
-}
+	groupBalances, err := s.groupBalances(userID, groups)
+	if err != nil {
+		log.Printf("Error calculating balances: %v", err)
+		jsonError(w, "Failed to calculate your balances. Please try again later.", http.StatusInternalServerError)
+		return
+	}
 
-func InsertTransactions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.UserBalances{
+		UserID: userID,
+		Totals: balance.Totals(groupBalances),
+		Groups: groupBalances,
+	})
+}
 
+// NetBalances offsets what the user and another person owe each other in
+// different groups by recording netting transactions, leaving the remainder
+// in a single group per currency
+func (s *Server) NetBalances(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	groupId, err := strconv.Atoi(vars["groupId"])
+	userID, err := strconv.ParseInt(vars["userId"], 10, 64)
 	if err != nil {
-		jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+		jsonError(w, "Invalid user ID. Please try again.", http.StatusBadRequest)
+		return
+	}
+	otherUserID, err := strconv.ParseInt(vars["otherUserId"], 10, 64)
+	if err != nil || otherUserID == userID {
+		jsonError(w, "Invalid user ID. Please try again.", http.StatusBadRequest)
 		return
 	}
-	var transaction model.Transactions
-	err = json.NewDecoder(r.Body).Decode(&transaction)
+
+	groups, err := s.fetchAllGroupsByUserID(userID)
 	if err != nil {
-		jsonError(w, "Invalid transaction data. Please check your information and try again.", http.StatusBadRequest)
+		jsonError(w, "Failed to fetch your groups. Please try again later.", http.StatusInternalServerError)
 		return
 	}
-	transaction.GroupID = int64(groupId)
-	query := `INSERT INTO transactions (user_id, payer_id, group_id, amount) VALUES ($1, $2, $3, $4) RETURNING id`
-	err = db.QueryRow(query, transaction.UserID, transaction.PayerID, groupId, transaction.Amount).Scan(&transaction.ID)
+
+	groupBalances, err := s.groupBalances(userID, groups)
 	if err != nil {
-		jsonError(w, "Failed to record transaction. Please try again later.", http.StatusInternalServerError)
+		log.Printf("Error calculating balances: %v", err)
+		jsonError(w, "Failed to calculate your balances. Please try again later.", http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(transaction)
+
+	transactions, remaining := balance.NetAcrossGroups(userID, otherUserID, groupBalances)
+	if len(remaining) == 0 {
+		jsonError(w, "There are no opposing balances with this person to net across groups.", http.StatusBadRequest)
+		return
+	}
+
+	if len(transactions) > 0 {
+		if err := s.transactions.CreateBatch(transactions); err != nil {
+			log.Printf("Error recording netting transactions: %v", err)
+			jsonError(w, "Failed to net your balances. Please try again later.", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.NettingResult{
+		Transactions: transactions,
+		Balances:     remaining,
+	})
 }
 
 func (s *ReminderService) SendMonthlyBalanceReminders() {
@@ -1531,77 +2598,34 @@ func (s *ReminderService) SendMonthlyBalanceReminders() {
 	startTime := time.Now()
 
 	// Create a job record
-	jobID, err := logReminderJob("monthly_balance", "running") // inserting new job in db (jobtype, status)
+	jobID, err := s.server.reminders.StartJob("monthly_balance", "running") // inserting new job in db (jobtype, status)
 	if err != nil {
 		log.Printf("Error logging reminder job: %v", err)
 	}
 
-	rows, err := db.Query("SELECT user_id, name, email FROM users")
+	users, err := s.server.users.List()
 	if err != nil {
 		log.Printf("Error fetching users: %v", err)
-		updateReminderJob(jobID, "failed", err.Error(), 0, 0)
+		s.server.reminders.FinishJob(jobID, "failed", err.Error(), 0, 0)
 		return
 	}
-	defer rows.Close()
-
-	var users []model.UserResponse
-
-	for rows.Next() {
-		var user model.UserResponse
-		if err := rows.Scan(&user.UserID, &user.Name, &user.Email); err != nil {
-			log.Printf("Error scanning user: %v", err)
-			continue
-		}
-		users = append(users, user)
-	}
 
 	successCount := 0
 	errorCount := 0
 
 	for _, user := range users {
-		groups, err := fetchAllGroupsByUserID(user.UserID)
+		groups, err := s.server.fetchAllGroupsByUserID(user.UserID)
 		if err != nil {
 			log.Printf("Error fetching groups for user %d: %v", user.UserID, err)
 			errorCount++
 			continue
 		}
 
-		var allBalances []model.Balance
-
-		for _, group := range groups {
-			otherUsers, err := getGroupUserIDs(int(group.GroupID), user.UserID)
-			if err != nil {
-				log.Printf("Error fetching group users: %v", err)
-				continue
-			}
-
-			if len(otherUsers) == 0 {
-				continue
-			}
-
-			settlements, err := calculateSettlements(int(group.GroupID), int(user.UserID), otherUsers)
-			if err != nil {
-				log.Printf("Error calculating settlements: %v", err)
-				continue
-			}
-
-			for _, settlement := range settlements {
-				var otherUserName string
-				err := db.QueryRow("SELECT name FROM users WHERE user_id = $1", settlement.UserID).Scan(&otherUserName)
-				if err != nil {
-					log.Printf("Error fetching user name: %v", err)
-					continue
-				}
-
-				balance := model.Balance{
-					OtherUserID:   settlement.UserID,
-					OtherUserName: otherUserName,
-					Amount:        settlement.ShareAmount,
-					GroupName:     group.GroupName,
-				}
-
-				allBalances = append(allBalances, balance)
-			}
+		allBalances, err := s.server.groupBalances(user.UserID, groups)
+		if err != nil {
+			log.Printf("Error calculating balances for user %d: %v", user.UserID, err)
+			errorCount++
+			continue
 		}
 
 		if len(allBalances) == 0 {
@@ -1614,7 +2638,7 @@ func (s *ReminderService) SendMonthlyBalanceReminders() {
 			errorCount++
 		} else {
 			// Log that reminder was sent
-			err = logReminderSent(user.UserID, "monthly_balance")
+			err = s.server.reminders.LogSent(user.UserID, "monthly_balance")
 			if err != nil {
 				log.Printf("Error logging reminder: %v", err)
 			}
@@ -1629,177 +2653,31 @@ func (s *ReminderService) SendMonthlyBalanceReminders() {
 	log.Printf("Completed monthly balance reminder job. Success: %d, Errors: %d, Duration: %v",
 		successCount, errorCount, duration)
 
-	updateReminderJob(jobID, "completed", "", successCount, errorCount)
+	s.server.reminders.FinishJob(jobID, "completed", "", successCount, errorCount)
 }
 
-func getGroupUserIDs(groupID int, excludeUserID int64) ([]int64, error) {
-	rows, err := db.Query(`
-		SELECT user_id 
-		FROM group_users 
-		WHERE group_id = $1 AND user_id != $2`,
-		groupID, excludeUserID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// requireAuthToken checks the X-Auth-Token header used by admin and
+// scheduled-job endpoints. It fails closed when AUTH_TOKEN is not set.
+func requireAuthToken(w http.ResponseWriter, r *http.Request) bool {
+	authToken := r.Header.Get("X-Auth-Token")
+	expectedToken := os.Getenv("AUTH_TOKEN")
 
-	var userIDs []int64
-	for rows.Next() {
-		var userID int64
-		if err := rows.Scan(&userID); err != nil {
-			return nil, err
-		}
-		userIDs = append(userIDs, userID)
+	if expectedToken == "" {
+		log.Printf("Rejecting %s %s: AUTH_TOKEN environment variable not set", r.Method, r.URL.Path)
+		jsonError(w, "This endpoint is not configured.", http.StatusServiceUnavailable)
+		return false
 	}
-
-	return userIDs, rows.Err()
-}
-
-func calculateSettlements(groupID, userID int, otherUserIDs []int64) ([]model.UserShare, error) {
-	var settlements []model.UserShare
-
-	for _, otherUserID := range otherUserIDs {
-		var totalAmount int64 = 0
-
-		rows1, err := db.Query("SELECT item_id FROM items WHERE group_id = $1 AND paid_by = $2",
-			groupID, otherUserID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch items: %w", err)
-		}
-		defer rows1.Close()
-
-		for rows1.Next() {
-			var itemID int64
-			if err := rows1.Scan(&itemID); err != nil {
-				return nil, fmt.Errorf("failed to scan items: %w", err)
-			}
-
-			var shareAmount int64
-			err = db.QueryRow("SELECT share FROM item_splits WHERE item_id = $1 AND user_id = $2",
-				itemID, int64(userID)).Scan(&shareAmount)
-
-			if err == sql.ErrNoRows {
-				continue
-			} else if err != nil {
-				return nil, fmt.Errorf("failed to get share: %w", err)
-			}
-
-			totalAmount += shareAmount
-		}
-
-		rows2, err := db.Query("SELECT amount FROM transactions WHERE group_id = $1 AND user_id = $2 AND payer_id = $3",
-			groupID, userID, otherUserID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch transactions: %w", err)
-		}
-		defer rows2.Close()
-
-		for rows2.Next() {
-			var amount int64
-			if err := rows2.Scan(&amount); err != nil {
-				return nil, fmt.Errorf("failed to scan transactions: %w", err)
-			}
-			totalAmount -= amount
-		}
-
-		rows3, err := db.Query("SELECT item_id FROM items WHERE group_id = $1 AND paid_by = $2",
-			groupID, userID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch items: %w", err)
-		}
-		defer rows3.Close()
-
-		for rows3.Next() {
-			var itemID int64
-			if err := rows3.Scan(&itemID); err != nil {
-				return nil, fmt.Errorf("failed to scan items: %w", err)
-			}
-
-			var shareAmount int64
-			err = db.QueryRow("SELECT share FROM item_splits WHERE item_id = $1 AND user_id = $2",
-				itemID, otherUserID).Scan(&shareAmount)
-
-			if err == sql.ErrNoRows {
-				continue
-			} else if err != nil {
-				return nil, fmt.Errorf("failed to get share: %w", err)
-			}
-
-			totalAmount -= shareAmount
-		}
-
-		rows4, err := db.Query("SELECT amount FROM transactions WHERE group_id = $1 AND user_id = $2 AND payer_id = $3",
-			groupID, otherUserID, userID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch transactions: %w", err)
-		}
-		defer rows4.Close()
-
-		for rows4.Next() {
-			var amount int64
-			if err := rows4.Scan(&amount); err != nil {
-				return nil, fmt.Errorf("failed to scan transactions: %w", err)
-			}
-			totalAmount += amount
-		}
-
-		if totalAmount != 0 {
-			settlements = append(settlements, model.UserShare{
-				UserID:      otherUserID,
-				ShareAmount: totalAmount,
-			})
-		}
+	if subtle.ConstantTimeCompare([]byte(authToken), []byte(expectedToken)) != 1 {
+		log.Printf("Unauthorized access attempt on %s %s", r.Method, r.URL.Path)
+		jsonError(w, "Unauthorized access. Please check your credentials and try again.", http.StatusUnauthorized)
+		return false
 	}
-
-	return settlements, nil
-}
-
-// logReminderJob records the start of a reminder job
-func logReminderJob(jobType, status string) (int64, error) {
-	query := `
-		INSERT INTO reminder_jobs (job_type, status, started_at)
-		VALUES ($1, $2, $3)
-		RETURNING id
-	`
-
-	var jobID int64
-	err := db.QueryRow(query, jobType, status, time.Now()).Scan(&jobID)
-	return jobID, err
-}
-
-// updateReminderJob updates the status of a reminder job
-func updateReminderJob(jobID int64, status, errorMsg string, success, failed int) error {
-	query := `
-		UPDATE reminder_jobs
-		SET status = $2, error = $3, success_count = $4, error_count = $5, completed_at = $6
-		WHERE id = $1
-	`
-
-	_, err := db.Exec(query, jobID, status, errorMsg, success, failed, time.Now())
-	return err
-}
-
-// logReminderSent records that a reminder was sent to a user
-func logReminderSent(userID int64, reminderType string) error {
-	query := `
-		INSERT INTO reminder_logs (user_id, reminder_type, sent_at)
-		VALUES ($1, $2, $3)
-	`
-
-	_, err := db.Exec(query, userID, reminderType, time.Now())
-	return err
+	return true
 }
 
-func TriggerMonthlyReminders(w http.ResponseWriter, r *http.Request) {
-
-	authToken := r.Header.Get("X-Auth-Token")
-	expectedToken := os.Getenv("AUTH_TOKEN")
+func (s *Server) TriggerMonthlyReminders(w http.ResponseWriter, r *http.Request) {
 
-	if expectedToken == "" {
-		log.Println("Warning: AUTH_TOKEN environment variable not set")
-	} else if authToken != expectedToken {
-		log.Printf("Unauthorized access attempt with token: %s", authToken)
-		jsonError(w, "Unauthorized access. Please check your credentials and try again.", http.StatusUnauthorized)
+	if !requireAuthToken(w, r) {
 		return
 	}
 
@@ -1814,7 +2692,7 @@ func TriggerMonthlyReminders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reminderService := newReminderService(emailService)
+	reminderService := s.newReminderService(emailService)
 
 	go func() {
 		log.Println("Starting monthly balance reminder job triggered by AWS Lambda")
@@ -1826,9 +2704,92 @@ func TriggerMonthlyReminders(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, `{"message":"Monthly balance reminder job started"}`)
 }
 
-func Ping(w http.ResponseWriter, r *http.Request) {
-     w.Header().Set("Content-Type", "application/json")
-     w.WriteHeader(http.StatusOK)
-    fmt.Fprintf(w, `{"message":"Backend is awake"}`)
+func (s *Server) UploadExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if !requireAuthToken(w, r) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		jsonError(w, "Failed to read request body.", http.StatusBadRequest)
+		return
+	}
+
+	var input []rates.Rate
+	if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+		input, err = rates.ParseCSV(bytes.NewReader(body))
+	} else {
+		input, err = rates.ParseJSON(body)
+	}
+	if err != nil {
+		jsonError(w, "Invalid rates. Please send date, base, quote and rate values as CSV or JSON.", http.StatusBadRequest)
+		return
+	}
+
+	for i := range input {
+		if err := input[i].Validate(); err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := s.exchangeRates.Save(input); err != nil {
+		log.Printf("Error saving exchange rates: %v", err)
+		jsonError(w, "Failed to save exchange rates. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Exchange rates saved successfully",
+		"count":   len(input),
+	})
+}
+
+func (s *Server) GetExchangeRate(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	base, err := rates.NormalizeCode(query.Get("base"))
+	if err != nil {
+		jsonError(w, "Please enter a valid 3-letter base currency code.", http.StatusBadRequest)
+		return
+	}
+	quote, err := rates.NormalizeCode(query.Get("quote"))
+	if err != nil {
+		jsonError(w, "Please enter a valid 3-letter quote currency code.", http.StatusBadRequest)
+		return
+	}
+
+	date := time.Now().UTC()
+	if dateStr := query.Get("date"); dateStr != "" {
+		date, err = time.Parse(time.DateOnly, dateStr)
+		if err != nil {
+			jsonError(w, "Invalid date. Please use the YYYY-MM-DD format.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	value, err := s.exchangeRates.On(date, base, quote)
+	if errors.Is(err, rates.ErrRateNotFound) {
+		jsonError(w, "No exchange rate is available for this date.", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error looking up exchange rate: %v", err)
+		jsonError(w, "Failed to look up the exchange rate. Please try again later.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates.Rate{
+		Date:  date.Format(time.DateOnly),
+		Base:  base,
+		Quote: quote,
+		Rate:  value,
+	})
 }
 
+func (s *Server) Ping(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `{"message":"Backend is awake"}`)
+}
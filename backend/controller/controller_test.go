@@ -0,0 +1,387 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-splitwise/controller"
+	"go-splitwise/model"
+	"go-splitwise/rates"
+	"go-splitwise/repository"
+	"go-splitwise/router"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// testServer is a server backed by in-memory repositories with a group of
+// members already set up and a session for each of them
+type testServer struct {
+	repos    repository.Repositories
+	rates    *rates.Store
+	handler  *mux.Router
+	groupID  int64
+	users    []int64
+	sessions map[int64]string
+}
+
+// newTestServer creates a group with n members
+func newTestServer(t *testing.T, n int) *testServer {
+	t.Helper()
+
+	repos := repository.NewInMemory()
+	store := rates.NewStore(repos.Rates)
+	ts := &testServer{
+		repos:    repos,
+		rates:    store,
+		handler:  router.Router(controller.NewServer(repos, store)),
+		sessions: make(map[int64]string),
+	}
+
+	group := model.Group{GroupName: "Flat"}
+	if err := repos.Groups.Create(&group); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	ts.groupID = group.GroupID
+
+	for i := 0; i < n; i++ {
+		userID := ts.addUser(t)
+		if err := repos.Groups.AddMember(ts.groupID, userID); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+	return ts
+}
+
+// addUser stores a user outside any group and logs them in
+func (ts *testServer) addUser(t *testing.T) int64 {
+	t.Helper()
+
+	n := len(ts.users) + 1
+	userID, err := ts.repos.Users.Create(fmt.Sprintf("User %d", n), fmt.Sprintf("user%d@example.com", n), "hash")
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	sessionID := fmt.Sprintf("session-%d", userID)
+	if err := ts.repos.Sessions.Create(sessionID, userID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	ts.users = append(ts.users, userID)
+	ts.sessions[userID] = sessionID
+	return userID
+}
+
+// do sends a request as userID and decodes a successful response into out
+func (ts *testServer) do(t *testing.T, userID int64, method, path, body string, out interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.AddCookie(&http.Cookie{Name: "session_token", Value: ts.sessions[userID]})
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// addExpense posts an expense as its payer and fails the test unless it is
+// accepted
+func (ts *testServer) addExpense(t *testing.T, body string) model.Expense {
+	t.Helper()
+
+	var expense model.Expense
+	path := fmt.Sprintf("/api/addExpense/%d", ts.groupID)
+	if code := ts.do(t, ts.users[0], http.MethodPost, path, body, &expense); code != http.StatusOK {
+		t.Fatalf("add expense %s: status %d", body, code)
+	}
+	return expense
+}
+
+func TestAddExpense(t *testing.T) {
+	ts := newTestServer(t, 3)
+	outsider := ts.addUser(t)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		shares map[int64]int64
+	}{
+		{
+			name:   "equal split charges the leftover unit to the payer",
+			body:   fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Dinner","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d},{"user_id":%d}]}`, a, a, b, c),
+			status: http.StatusOK,
+			shares: map[int64]int64{a: 666, b: -333, c: -333},
+		},
+		{
+			name:   "exact split with two payers",
+			body:   fmt.Sprintf(`{"amount":900,"payers":[{"user_id":%d,"amount":600},{"user_id":%d,"amount":300}],"description":"Cab","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":300},{"user_id":%d,"share_amount":300},{"user_id":%d,"share_amount":300}]}`, a, b, a, b, c),
+			status: http.StatusOK,
+			shares: map[int64]int64{a: 300, b: 0, c: -300},
+		},
+		{
+			name:   "adjustments on top of an equal split",
+			body:   fmt.Sprintf(`{"amount":30,"payer_id":%d,"description":"Drinks","expense_type":"ADJUSTMENT","user_shares":[{"user_id":%d,"share_amount":5},{"user_id":%d,"share_amount":0}]}`, b, a, b),
+			status: http.StatusOK,
+			shares: map[int64]int64{a: -17, b: 17},
+		},
+		{
+			name:   "adjustment listing a user twice",
+			body:   fmt.Sprintf(`{"amount":30,"payer_id":%d,"description":"Drinks","expense_type":"ADJUSTMENT","user_shares":[{"user_id":%d,"share_amount":5},{"user_id":%d,"share_amount":5},{"user_id":%d,"share_amount":0}]}`, b, a, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "equal split listing a user twice",
+			body:   fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Dinner","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d},{"user_id":%d}]}`, a, a, b, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "shares with weights too large to multiply in 64 bits",
+			body:   fmt.Sprintf(`{"amount":3000000000000,"payer_id":%d,"description":"Yacht","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":1000000000000000},{"user_id":%d,"share_amount":2000000000000000}]}`, a, a, b),
+			status: http.StatusOK,
+			shares: map[int64]int64{a: 2000000000000, b: -2000000000000},
+		},
+		{
+			name:   "shares adding up past the largest weight",
+			body:   fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Yacht","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":9000000000000000000},{"user_id":%d,"share_amount":9000000000000000000}]}`, a, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "zero amount",
+			body:   fmt.Sprintf(`{"amount":0,"payer_id":%d,"description":"Nothing","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "exact shares not adding up",
+			body:   fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Dinner","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":300},{"user_id":%d,"share_amount":300}]}`, a, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "share for a non-member",
+			body:   fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Dinner","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, outsider),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "paid by a non-member",
+			body:   fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Dinner","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, outsider, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "currency without a rate",
+			body:   fmt.Sprintf(`{"amount":1000,"currency":"EUR","payer_id":%d,"description":"Museum","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "malformed body",
+			body:   `{"amount":`,
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var expense model.Expense
+			path := fmt.Sprintf("/api/addExpense/%d", ts.groupID)
+			if code := ts.do(t, a, http.MethodPost, path, tt.body, &expense); code != tt.status {
+				t.Fatalf("status %d, want %d", code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			shares, err := ts.repos.Splits.ListByItem(expense.ExpenseID)
+			if err != nil {
+				t.Fatalf("ListByItem: %v", err)
+			}
+			got := make(map[int64]int64)
+			for _, share := range shares {
+				got[share.UserID] = share.ShareAmount
+			}
+			for userID, want := range tt.shares {
+				if got[userID] != want {
+					t.Errorf("user %d's share is %d, want %d", userID, got[userID], want)
+				}
+			}
+		})
+	}
+
+	items, err := ts.repos.Items.ListByGroup(ts.groupID, repository.ItemFilter{})
+	if err != nil {
+		t.Fatalf("ListByGroup: %v", err)
+	}
+	if len(items) != 4 {
+		t.Errorf("group has %d expenses, want only the 4 accepted ones", len(items))
+	}
+}
+
+func TestSharesSplit(t *testing.T) {
+	ts := newTestServer(t, 3)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	// 2, 3 and 2 nights of a 1000 cabin: 285.71, 428.57 and 285.71 each
+	cabin := fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Cabin","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":2},{"user_id":%d,"share_amount":3},{"user_id":%d,"share_amount":2}]}`, a, a, b, c)
+
+	tests := []struct {
+		name      string
+		policy    string
+		body      string
+		status    int
+		shares    map[int64]int64
+		remainder int64
+	}{
+		{
+			name:      "the payer absorbs the leftover units",
+			policy:    "PAYER_ABSORBS",
+			body:      cabin,
+			status:    http.StatusOK,
+			shares:    map[int64]int64{a: 713, b: -428, c: -285},
+			remainder: 2,
+		},
+		{
+			name:      "largest remainders take the leftover units, lowest user ID first",
+			policy:    "LARGEST_REMAINDER",
+			body:      cabin,
+			status:    http.StatusOK,
+			shares:    map[int64]int64{a: 714, b: -428, c: -286},
+			remainder: 2,
+		},
+		{
+			name:   "weights that divide the amount exactly",
+			policy: "LARGEST_REMAINDER",
+			body:   fmt.Sprintf(`{"amount":900,"payer_id":%d,"description":"Rental","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":1},{"user_id":%d,"share_amount":2}]}`, b, a, b),
+			status: http.StatusOK,
+			shares: map[int64]int64{a: -300, b: 300},
+		},
+		{
+			name:   "a zero weight owes nothing",
+			policy: "LARGEST_REMAINDER",
+			body:   fmt.Sprintf(`{"amount":500,"payer_id":%d,"description":"Fuel","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":1},{"user_id":%d,"share_amount":0}]}`, a, b, c),
+			status: http.StatusOK,
+			shares: map[int64]int64{a: 500, b: -500, c: 0},
+		},
+		{
+			name:   "negative weight",
+			policy: "LARGEST_REMAINDER",
+			body:   fmt.Sprintf(`{"amount":500,"payer_id":%d,"description":"Fuel","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":2},{"user_id":%d,"share_amount":-1}]}`, a, a, b),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "all weights zero",
+			policy: "LARGEST_REMAINDER",
+			body:   fmt.Sprintf(`{"amount":500,"payer_id":%d,"description":"Fuel","expense_type":"SHARES","user_shares":[{"user_id":%d,"share_amount":0},{"user_id":%d,"share_amount":0}]}`, a, a, b),
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/api/groups/%d/rounding-policy", ts.groupID)
+			if code := ts.do(t, a, http.MethodPut, path, fmt.Sprintf(`{"rounding_policy":%q}`, tt.policy), nil); code != http.StatusOK {
+				t.Fatalf("set rounding policy: status %d", code)
+			}
+
+			var expense model.Expense
+			path = fmt.Sprintf("/api/addExpense/%d", ts.groupID)
+			if code := ts.do(t, a, http.MethodPost, path, tt.body, &expense); code != tt.status {
+				t.Fatalf("status %d, want %d", code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			if got := ts.splitsOf(t, expense.ExpenseID); !reflect.DeepEqual(got, tt.shares) {
+				t.Errorf("shares %v, want %v", got, tt.shares)
+			}
+			if expense.RoundingRemainder != tt.remainder {
+				t.Errorf("rounding remainder %d, want %d", expense.RoundingRemainder, tt.remainder)
+			}
+		})
+	}
+}
+
+func TestSettlements(t *testing.T) {
+	ts := newTestServer(t, 3)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	err := ts.rates.Save([]rates.Rate{{Date: "2026-01-01", Base: "USD", Quote: "INR", Rate: 80}})
+	if err != nil {
+		t.Fatalf("save rates: %v", err)
+	}
+
+	// a pays 900 for everyone, b pays USD 10 split with c, and c pays a back 100
+	ts.addExpense(t, fmt.Sprintf(`{"amount":900,"payer_id":%d,"description":"Groceries","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d},{"user_id":%d}]}`, a, a, b, c))
+	ts.addExpense(t, fmt.Sprintf(`{"amount":10,"currency":"USD","expense_date":"2026-02-01","payer_id":%d,"description":"Tickets","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, b, b, c))
+	ts.addExpense(t, fmt.Sprintf(`{"amount":100,"payer_id":%d,"description":"Repayment","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":100}]}`, c, a))
+
+	// a: +600 - 100 = 500, b: -300 + 400 = 100, c: -300 - 400 + 100 = -600
+	want := map[int64]int64{a: 500, b: 100, c: -600}
+
+	t.Run("settle plan", func(t *testing.T) {
+		var plan model.SettlePlan
+		path := fmt.Sprintf("/api/groups/%d/settle-plan", ts.groupID)
+		if code := ts.do(t, a, http.MethodGet, path, "", &plan); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		if plan.Currency != rates.DefaultCurrency {
+			t.Errorf("currency %q, want %q", plan.Currency, rates.DefaultCurrency)
+		}
+
+		for _, balance := range plan.Balances {
+			if balance.ShareAmount != want[balance.UserID] {
+				t.Errorf("user %d's balance is %d, want %d", balance.UserID, balance.ShareAmount, want[balance.UserID])
+			}
+		}
+
+		net := make(map[int64]int64)
+		for _, transfer := range plan.Transfers {
+			if transfer.Amount <= 0 {
+				t.Errorf("transfer %+v is not positive", transfer)
+			}
+			net[transfer.FromUserID] -= transfer.Amount
+			net[transfer.ToUserID] += transfer.Amount
+		}
+		for userID, balance := range want {
+			if net[userID] != balance {
+				t.Errorf("transfers settle user %d by %d, want %d", userID, net[userID], balance)
+			}
+		}
+		if len(plan.Transfers) != 2 {
+			t.Errorf("%d transfers, want 2", len(plan.Transfers))
+		}
+	})
+
+	t.Run("settlements", func(t *testing.T) {
+		var settlements []struct {
+			UserID      int64  `json:"user_id"`
+			ShareAmount int64  `json:"share_amount"`
+			Currency    string `json:"currency"`
+		}
+		path := fmt.Sprintf("/api/settlements/%d/%d", ts.groupID, c)
+		body := fmt.Sprintf(`{"users":[%d,%d]}`, a, b)
+		if code := ts.do(t, c, http.MethodPost, path, body, &settlements); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+
+		// c owes a 300 - 100 and b 400, reported from c's side
+		wantPair := map[int64]int64{a: -200, b: -400}
+		if len(settlements) != len(wantPair) {
+			t.Fatalf("%d settlements, want %d", len(settlements), len(wantPair))
+		}
+		for _, settlement := range settlements {
+			if settlement.ShareAmount != wantPair[settlement.UserID] {
+				t.Errorf("c's balance with user %d is %d, want %d", settlement.UserID, settlement.ShareAmount, wantPair[settlement.UserID])
+			}
+			if settlement.Currency != rates.DefaultCurrency {
+				t.Errorf("currency %q, want %q", settlement.Currency, rates.DefaultCurrency)
+			}
+		}
+	})
+}
@@ -0,0 +1,164 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// splitsOf returns each user's stored share of an expense
+func (ts *testServer) splitsOf(t *testing.T, expenseID int64) map[int64]int64 {
+	t.Helper()
+
+	shares, err := ts.repos.Splits.ListByItem(expenseID)
+	if err != nil {
+		t.Fatalf("ListByItem: %v", err)
+	}
+	got := make(map[int64]int64)
+	for _, share := range shares {
+		got[share.UserID] = share.ShareAmount
+	}
+	return got
+}
+
+// balancesOf returns each member's net balance from the group's settle plan
+func (ts *testServer) balancesOf(t *testing.T) map[int64]int64 {
+	t.Helper()
+
+	var plan model.SettlePlan
+	path := fmt.Sprintf("/api/groups/%d/settle-plan", ts.groupID)
+	if code := ts.do(t, ts.users[0], http.MethodGet, path, "", &plan); code != http.StatusOK {
+		t.Fatalf("settle plan: status %d", code)
+	}
+	got := make(map[int64]int64)
+	for _, balance := range plan.Balances {
+		if balance.ShareAmount != 0 {
+			got[balance.UserID] = balance.ShareAmount
+		}
+	}
+	return got
+}
+
+func TestUpdateExpense(t *testing.T) {
+	ts := newTestServer(t, 3)
+	outsider := ts.addUser(t)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	expense := ts.addExpense(t, fmt.Sprintf(`{"amount":900,"payer_id":%d,"description":"Groceries","expense_date":"2026-03-01","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d},{"user_id":%d}]}`, a, a, b, c))
+	path := fmt.Sprintf("/api/expenses/%d", expense.ExpenseID)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		// shares and balances after the request
+		shares   map[int64]int64
+		balances map[int64]int64
+	}{
+		{
+			name:     "fix the amount and split it exactly",
+			path:     path,
+			body:     fmt.Sprintf(`{"amount":1200,"payer_id":%d,"description":"Groceries","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":200},{"user_id":%d,"share_amount":1000}]}`, a, a, b),
+			status:   http.StatusOK,
+			shares:   map[int64]int64{a: 1000, b: -1000},
+			balances: map[int64]int64{a: 1000, b: -1000},
+		},
+		{
+			name:     "change the payer and split by percentage",
+			path:     path,
+			body:     fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Groceries","expense_type":"PERCENTAGE","user_shares":[{"user_id":%d,"share_amount":50},{"user_id":%d,"share_amount":50}]}`, c, a, c),
+			status:   http.StatusOK,
+			shares:   map[int64]int64{a: -500, c: 500},
+			balances: map[int64]int64{a: -500, c: 500},
+		},
+		{
+			name:     "exact shares that don't add up leave the expense as it was",
+			path:     path,
+			body:     fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Groceries","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":300}]}`, a, b),
+			status:   http.StatusBadRequest,
+			shares:   map[int64]int64{a: -500, c: 500},
+			balances: map[int64]int64{a: -500, c: 500},
+		},
+		{
+			name:     "sharing with someone outside the group",
+			path:     path,
+			body:     fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Groceries","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, outsider),
+			status:   http.StatusBadRequest,
+			shares:   map[int64]int64{a: -500, c: 500},
+			balances: map[int64]int64{a: -500, c: 500},
+		},
+		{
+			name:     "missing expense",
+			path:     fmt.Sprintf("/api/expenses/%d", expense.ExpenseID+1000),
+			body:     fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Groceries","expense_type":"EQUAL","user_shares":[{"user_id":%d}]}`, a, a),
+			status:   http.StatusNotFound,
+			shares:   map[int64]int64{a: -500, c: 500},
+			balances: map[int64]int64{a: -500, c: 500},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated model.Expense
+			if code := ts.do(t, a, http.MethodPut, tt.path, tt.body, &updated); code != tt.status {
+				t.Fatalf("status %d, want %d", code, tt.status)
+			}
+			if got := ts.splitsOf(t, expense.ExpenseID); !reflect.DeepEqual(got, tt.shares) {
+				t.Errorf("shares %v, want %v", got, tt.shares)
+			}
+			if got := ts.balancesOf(t); !reflect.DeepEqual(got, tt.balances) {
+				t.Errorf("balances %v, want %v", got, tt.balances)
+			}
+		})
+	}
+
+	stored, err := ts.repos.Items.Get(expense.ExpenseID)
+	if err != nil {
+		t.Fatalf("get expense: %v", err)
+	}
+	if stored.Amount != 1000 || stored.PayerID != c || stored.ExpenseType != "PERCENTAGE" {
+		t.Errorf("stored expense %+v doesn't match the last accepted update", stored)
+	}
+	if stored.ExpenseDate != "2026-03-01" {
+		t.Errorf("expense date %q, want the original 2026-03-01 kept when left out", stored.ExpenseDate)
+	}
+}
+
+func TestDeleteExpense(t *testing.T) {
+	ts := newTestServer(t, 2)
+	a, b := ts.users[0], ts.users[1]
+
+	kept := ts.addExpense(t, fmt.Sprintf(`{"amount":400,"payer_id":%d,"description":"Cab","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, b, a, b))
+	typo := ts.addExpense(t, fmt.Sprintf(`{"amount":90000,"payer_id":%d,"description":"Groceries","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b))
+	path := fmt.Sprintf("/api/expenses/%d", typo.ExpenseID)
+
+	if code := ts.do(t, a, http.MethodDelete, path, "", nil); code != http.StatusOK {
+		t.Fatalf("delete: status %d", code)
+	}
+
+	if got := ts.splitsOf(t, typo.ExpenseID); len(got) != 0 {
+		t.Errorf("deleted expense still has shares %v", got)
+	}
+	want := map[int64]int64{a: -200, b: 200}
+	if got := ts.balancesOf(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("balances %v, want %v from the remaining expense", got, want)
+	}
+
+	items, err := ts.repos.Items.ListByGroup(ts.groupID, repository.ItemFilter{})
+	if err != nil {
+		t.Fatalf("ListByGroup: %v", err)
+	}
+	if len(items) != 1 || items[0].ExpenseID != kept.ExpenseID {
+		t.Errorf("group lists %d expenses, want only the one kept", len(items))
+	}
+
+	if code := ts.do(t, a, http.MethodDelete, path, "", nil); code != http.StatusNotFound {
+		t.Errorf("deleting again: status %d, want %d", code, http.StatusNotFound)
+	}
+	if code := ts.do(t, a, http.MethodDelete, "/api/expenses/abc", "", nil); code != http.StatusBadRequest {
+		t.Errorf("deleting an invalid ID: status %d, want %d", code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,49 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/model"
+	"net/http"
+	"testing"
+)
+
+func TestUpdateGroupSettings(t *testing.T) {
+	ts := newTestServer(t, 1)
+	a := ts.users[0]
+	path := func(setting string) string {
+		return fmt.Sprintf("/api/groups/%d/%s", ts.groupID, setting)
+	}
+
+	var group model.Group
+	if code := ts.do(t, a, http.MethodPut, path("rounding-policy"), `{"rounding_policy":"round_robin"}`, &group); code != http.StatusOK {
+		t.Fatalf("setting the rounding policy: status %d", code)
+	}
+	if group.RoundingPolicy != "ROUND_ROBIN" || group.BaseCurrency != "INR" {
+		t.Errorf("after setting the rounding policy got %+v, want ROUND_ROBIN in the default INR", group)
+	}
+
+	group = model.Group{}
+	if code := ts.do(t, a, http.MethodPut, path("base-currency"), `{"base_currency":"usd"}`, &group); code != http.StatusOK {
+		t.Fatalf("setting the base currency: status %d", code)
+	}
+	if group.RoundingPolicy != "ROUND_ROBIN" || group.BaseCurrency != "USD" {
+		t.Errorf("after setting the base currency got %+v, want ROUND_ROBIN in USD", group)
+	}
+
+	group = model.Group{}
+	if code := ts.do(t, a, http.MethodPut, path("rounding-policy"), `{"rounding_policy":"PAYER_ABSORBS"}`, &group); code != http.StatusOK {
+		t.Fatalf("setting the rounding policy: status %d", code)
+	}
+	if group.RoundingPolicy != "PAYER_ABSORBS" || group.BaseCurrency != "USD" {
+		t.Errorf("after setting the rounding policy again got %+v, want PAYER_ABSORBS in USD", group)
+	}
+
+	// Transactions are listed in the group's currency
+	var page model.TransactionPage
+	if code := ts.do(t, a, http.MethodGet, fmt.Sprintf("/api/getTransactions/%d", ts.groupID), "", &page); code != http.StatusOK {
+		t.Fatalf("listing transactions: status %d", code)
+	}
+	if page.Currency != "USD" {
+		t.Errorf("transactions are in %q, want USD", page.Currency)
+	}
+}
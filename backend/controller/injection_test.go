@@ -0,0 +1,169 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// injectionPayloads are values that must never be taken for an ID. Dot
+// segments are left out as the router redirects to the cleaned path before
+// any handler runs.
+var injectionPayloads = []string{
+	"1 OR 1=1",
+	"1' OR '1'='1",
+	"1; DROP TABLE items",
+	"1--",
+	"1/**/UNION/**/SELECT/**/1",
+	"1)",
+	"' OR sleep(5)--",
+	"1%00",
+	"1e3",
+	"0x10",
+	"+1 ",
+	"99999999999999999999",
+	"abc",
+}
+
+// snapshot captures everything a request could change
+func snapshot(t *testing.T, ts *testServer) map[string]interface{} {
+	t.Helper()
+
+	state := make(map[string]interface{})
+	check := func(name string, value interface{}, err error) {
+		if err != nil {
+			t.Fatalf("snapshot %s: %v", name, err)
+		}
+		state[name] = value
+	}
+
+	users, err := ts.repos.Users.List()
+	check("users", users, err)
+	group, err := ts.repos.Groups.Get(ts.groupID)
+	check("group", *group, err)
+	groups, err := ts.repos.Groups.ListByUser(ts.users[0])
+	check("groups", groups, err)
+	members, err := ts.repos.Groups.MemberIDs(ts.groupID)
+	check("members", members, err)
+	categories, err := ts.repos.Groups.Categories(ts.groupID)
+	check("categories", categories, err)
+	items, err := ts.repos.Items.ListByGroup(ts.groupID, repository.ItemFilter{})
+	check("items", items, err)
+	for _, item := range items {
+		shares, err := ts.repos.Splits.ListByItem(item.ExpenseID)
+		check(fmt.Sprintf("shares %d", item.ExpenseID), shares, err)
+	}
+	transactions, err := ts.repos.Transactions.ListByGroup(ts.groupID, repository.TransactionFilter{})
+	check("transactions", transactions, err)
+	recurring, err := ts.repos.Recurring.ListByGroup(ts.groupID)
+	check("recurring", recurring, err)
+	memories, err := ts.repos.Memories.ListByGroup(ts.groupID)
+	check("memories", memories, err)
+	return state
+}
+
+var routeVariable = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestRoutesRejectInjectedIDs(t *testing.T) {
+	ts := newTestServer(t, 2)
+	a, b := ts.users[0], ts.users[1]
+
+	expense := ts.addExpense(t, fmt.Sprintf(`{"amount":100,"payer_id":%d,"description":"Milk","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b))
+	transaction := model.Transactions{GroupID: ts.groupID, PayerID: b, UserID: a, Amount: 50}
+	if err := ts.repos.Transactions.Create(&transaction); err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	recurring := model.RecurringExpense{GroupID: ts.groupID, Amount: 100, PayerID: a, Description: "Rent", ExpenseType: "EQUAL", Cadence: "monthly", Active: true}
+	if err := ts.repos.Recurring.Create(&recurring); err != nil {
+		t.Fatalf("create recurring expense: %v", err)
+	}
+	memory, err := ts.repos.Memories.Create(ts.groupID, "photo.jpg", "https://example.com/photo.jpg")
+	if err != nil {
+		t.Fatalf("create memory: %v", err)
+	}
+
+	// Valid values for every variable but the one under test, so a request
+	// only fails because of the injected value
+	valid := map[string]string{
+		"userId":             strconv.FormatInt(a, 10),
+		"otherUserId":        strconv.FormatInt(b, 10),
+		"groupId":            strconv.FormatInt(ts.groupID, 10),
+		"expenseId":          strconv.FormatInt(expense.ExpenseID, 10),
+		"transactionId":      strconv.FormatInt(transaction.ID, 10),
+		"recurringExpenseId": strconv.FormatInt(recurring.ID, 10),
+		"memoryId":           strconv.Itoa(memory.ID),
+	}
+
+	type route struct {
+		template string
+		method   string
+	}
+	var routes []route
+	err = ts.handler.Walk(func(r *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := r.GetPathTemplate()
+		if err != nil || !routeVariable.MatchString(template) {
+			return nil
+		}
+		methods, err := r.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, method := range methods {
+			routes = append(routes, route{template, method})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	tested := make(map[string]bool)
+	before := snapshot(t, ts)
+	for _, rt := range routes {
+		for _, match := range routeVariable.FindAllStringSubmatch(rt.template, -1) {
+			variable := match[1]
+			if _, ok := valid[variable]; !ok {
+				t.Fatalf("no valid value for {%s} in %s", variable, rt.template)
+			}
+			tested[variable] = true
+
+			for _, payload := range injectionPayloads {
+				path := routeVariable.ReplaceAllStringFunc(rt.template, func(v string) string {
+					name := strings.Trim(v, "{}")
+					if name == variable {
+						return url.PathEscape(payload)
+					}
+					return valid[name]
+				})
+
+				name := fmt.Sprintf("%s %s with {%s}=%q", rt.method, rt.template, variable, payload)
+				code := ts.do(t, a, rt.method, path, "{}", nil)
+				if code != http.StatusBadRequest && code != http.StatusNotFound {
+					t.Errorf("%s: status %d, want 400 or 404", name, code)
+				}
+			}
+		}
+	}
+
+	for _, variable := range []string{"groupId", "userId", "expenseId", "transactionId", "recurringExpenseId", "memoryId"} {
+		if !tested[variable] {
+			t.Errorf("no route takes {%s}", variable)
+		}
+	}
+
+	after := snapshot(t, ts)
+	for name, value := range before {
+		if !reflect.DeepEqual(value, after[name]) {
+			t.Errorf("%s changed from %+v to %+v", name, value, after[name])
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	model "go-splitwise/model"
+	"go-splitwise/repository"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey string
+
+const sessionUserKey contextKey = "sessionUser"
+
+var (
+	errInvalidSession = errors.New("invalid session")
+	errSessionExpired = errors.New("session expired")
+)
+
+// groupResourceResolvers maps route variables that identify a resource owned
+// by a group to a lookup of the owning group
+func (s *Server) groupResourceResolvers() map[string]func(id int64) (int64, error) {
+	return map[string]func(id int64) (int64, error){
+		"expenseId": func(id int64) (int64, error) {
+			item, err := s.items.Get(id)
+			if err != nil {
+				return 0, err
+			}
+			return item.GroupID, nil
+		},
+		"transactionId": func(id int64) (int64, error) {
+			transaction, err := s.transactions.Get(id)
+			if err != nil {
+				return 0, err
+			}
+			return transaction.GroupID, nil
+		},
+		"recurringExpenseId": func(id int64) (int64, error) {
+			recurring, err := s.recurring.Get(id)
+			if err != nil {
+				return 0, err
+			}
+			return recurring.GroupID, nil
+		},
+		"memoryId": func(id int64) (int64, error) {
+			memory, err := s.memories.Get(id)
+			if err != nil {
+				return 0, err
+			}
+			return int64(memory.GroupID), nil
+		},
+	}
+}
+
+// getSessionUser resolves a session token to the user it belongs to
+func (s *Server) getSessionUser(sessionToken string) (*model.UserResponse, error) {
+	userID, expiresAt, err := s.sessions.Get(sessionToken)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errInvalidSession
+	} else if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(expiresAt) {
+		_ = s.sessions.Delete(sessionToken)
+		return nil, errSessionExpired
+	}
+
+	user, err := s.users.Get(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errInvalidSession
+	} else if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// sessionUser returns the authenticated user injected by RequireSession
+func sessionUser(r *http.Request) *model.UserResponse {
+	user, _ := r.Context().Value(sessionUserKey).(*model.UserResponse)
+	return user
+}
+
+// requireGroupMember writes an error response and returns false when the
+// session user does not belong to the group
+func (s *Server) requireGroupMember(w http.ResponseWriter, r *http.Request, groupID int64) bool {
+	user := sessionUser(r)
+	if user == nil {
+		jsonError(w, "Please log in to continue.", http.StatusUnauthorized)
+		return false
+	}
+
+	member, err := s.groups.IsMember(groupID, user.UserID)
+	if err != nil {
+		log.Printf("Error checking group membership: %v", err)
+		jsonError(w, "We're experiencing technical difficulties. Please try again later.", http.StatusInternalServerError)
+		return false
+	}
+	if !member {
+		jsonError(w, "You are not a member of this group.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// RequireSession authenticates the request from the session_token cookie and
+// rejects callers acting on another user's behalf or on groups they are not a
+// member of
+func (s *Server) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session_token")
+		if err != nil {
+			jsonError(w, "Please log in to continue.", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := s.getSessionUser(cookie.Value)
+		if errors.Is(err, errSessionExpired) {
+			jsonError(w, "Your session has expired. Please log in again to continue.", http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, errInvalidSession) {
+			jsonError(w, "Invalid session. Please log in again.", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			log.Printf("Error looking up session: %v", err)
+			jsonError(w, "We're experiencing technical difficulties. Please try again later.", http.StatusInternalServerError)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), sessionUserKey, user))
+		vars := mux.Vars(r)
+
+		if userIDStr, ok := vars["userId"]; ok {
+			userID, err := strconv.ParseInt(userIDStr, 10, 64)
+			if err != nil {
+				jsonError(w, "Invalid user ID. Please try again.", http.StatusBadRequest)
+				return
+			}
+			if userID != user.UserID {
+				jsonError(w, "You are not allowed to act on behalf of another user.", http.StatusForbidden)
+				return
+			}
+		}
+
+		if groupIDStr, ok := vars["groupId"]; ok {
+			groupID, err := strconv.ParseInt(groupIDStr, 10, 64)
+			if err != nil {
+				jsonError(w, "Invalid group ID. Please try again.", http.StatusBadRequest)
+				return
+			}
+			if !s.requireGroupMember(w, r, groupID) {
+				return
+			}
+		}
+
+		for name, resolve := range s.groupResourceResolvers() {
+			idStr, ok := vars[name]
+			if !ok {
+				continue
+			}
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				jsonError(w, "Invalid ID. Please try again.", http.StatusBadRequest)
+				return
+			}
+			groupID, err := resolve(id)
+			if errors.Is(err, repository.ErrNotFound) {
+				jsonError(w, "The requested resource was not found.", http.StatusNotFound)
+				return
+			} else if err != nil {
+				log.Printf("Error resolving group for %s: %v", name, err)
+				jsonError(w, "We're experiencing technical difficulties. Please try again later.", http.StatusInternalServerError)
+				return
+			}
+			if !s.requireGroupMember(w, r, groupID) {
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,115 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/model"
+	"go-splitwise/rates"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// uploadRates posts a rate snapshot with the admin token
+func (ts *testServer) uploadRates(t *testing.T, contentType, body string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/rates", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Auth-Token", "test-token")
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestExchangeRatePinning(t *testing.T) {
+	t.Setenv("AUTH_TOKEN", "test-token")
+	ts := newTestServer(t, 2)
+	a, b := ts.users[0], ts.users[1]
+
+	if code := ts.uploadRates(t, "application/json", `{"date":"2026-01-01","rates":[{"base":"USD","quote":"INR","rate":80}]}`); code != http.StatusOK {
+		t.Fatalf("upload rates: status %d", code)
+	}
+	tickets := fmt.Sprintf(`{"amount":10,"currency":"USD","expense_date":"2026-02-01","payer_id":%d,"description":"Tickets","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b)
+	before := ts.addExpense(t, tickets)
+	if before.ExchangeRate != 80 || before.ExchangeRateCurrency != rates.DefaultCurrency {
+		t.Fatalf("pinned %v %s, want 80 %s", before.ExchangeRate, before.ExchangeRateCurrency, rates.DefaultCurrency)
+	}
+
+	// A correction published for a day before the expense, and a later rate
+	body := "date,base,quote,rate\n2026-01-15,USD,INR,90\n2026-03-01,USD,INR,100\n"
+	if code := ts.uploadRates(t, "text/csv", body); code != http.StatusOK {
+		t.Fatalf("upload rates: status %d", code)
+	}
+
+	stored, err := ts.repos.Items.Get(before.ExpenseID)
+	if err != nil {
+		t.Fatalf("get expense: %v", err)
+	}
+	if stored.ExchangeRate != 80 {
+		t.Errorf("stored expense moved to rate %v, want the pinned 80", stored.ExchangeRate)
+	}
+	want := map[int64]int64{a: 400, b: -400}
+	if got := ts.balancesOf(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("balances %v after new rates, want %v", got, want)
+	}
+
+	// The same expense added now pins the rate in effect on its date
+	after := ts.addExpense(t, tickets)
+	if after.ExchangeRate != 90 {
+		t.Errorf("new expense pinned %v, want 90", after.ExchangeRate)
+	}
+	want = map[int64]int64{a: 850, b: -850}
+	if got := ts.balancesOf(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("balances %v, want %v", got, want)
+	}
+
+	lookups := []struct {
+		query  string
+		status int
+		rate   float64
+	}{
+		{"base=USD&quote=INR&date=2026-02-01", http.StatusOK, 90},
+		{"base=usd&quote=inr&date=2026-03-05", http.StatusOK, 100},
+		{"base=INR&quote=USD&date=2026-01-01", http.StatusOK, 1.0 / 80},
+		{"base=USD&quote=INR&date=2025-12-31", http.StatusNotFound, 0},
+		{"base=USD&quote=INR&date=01-02-2026", http.StatusBadRequest, 0},
+		{"base=US&quote=INR", http.StatusBadRequest, 0},
+	}
+	for _, lookup := range lookups {
+		var rate rates.Rate
+		if code := ts.do(t, a, http.MethodGet, "/api/rates?"+lookup.query, "", &rate); code != lookup.status {
+			t.Errorf("GET /api/rates?%s: status %d, want %d", lookup.query, code, lookup.status)
+			continue
+		}
+		if lookup.status == http.StatusOK && rate.Rate != lookup.rate {
+			t.Errorf("GET /api/rates?%s: rate %v, want %v", lookup.query, rate.Rate, lookup.rate)
+		}
+	}
+}
+
+func TestUploadExchangeRatesRejects(t *testing.T) {
+	t.Setenv("AUTH_TOKEN", "test-token")
+	ts := newTestServer(t, 1)
+
+	for name, body := range map[string]string{
+		"not JSON":          `rates`,
+		"invalid currency":  `[{"date":"2026-01-01","base":"US","quote":"INR","rate":80}]`,
+		"non-positive rate": `[{"date":"2026-01-01","base":"USD","quote":"INR","rate":0}]`,
+		"same currencies":   `[{"date":"2026-01-01","base":"INR","quote":"INR","rate":1}]`,
+	} {
+		if code := ts.uploadRates(t, "application/json", body); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", name, code, http.StatusBadRequest)
+		}
+	}
+
+	if _, err := ts.rates.Latest("USD", "INR"); err == nil {
+		t.Error("a rejected upload saved rates")
+	}
+	var expense model.Expense
+	if code := ts.do(t, ts.users[0], http.MethodPost, fmt.Sprintf("/api/addExpense/%d", ts.groupID),
+		fmt.Sprintf(`{"amount":10,"currency":"USD","payer_id":%d,"description":"Tickets","expense_type":"EQUAL","user_shares":[{"user_id":%d}]}`, ts.users[0], ts.users[0]), &expense); code != http.StatusBadRequest {
+		t.Errorf("expense in a currency without rates: status %d, want %d", code, http.StatusBadRequest)
+	}
+}
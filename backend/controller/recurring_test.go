@@ -0,0 +1,121 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/controller"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateRecurringExpense(t *testing.T) {
+	ts := newTestServer(t, 2)
+	a, b := ts.users[0], ts.users[1]
+	path := fmt.Sprintf("/api/groups/%d/recurring-expenses", ts.groupID)
+
+	tests := []struct {
+		name    string
+		cadence string
+		startAt time.Time
+		status  int
+	}{
+		{"starting tomorrow", "monthly", time.Now().Add(24 * time.Hour), http.StatusOK},
+		{"starting now", "daily", time.Now(), http.StatusOK},
+		{"starting in the past", "monthly", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), http.StatusBadRequest},
+		{"invalid cadence", "fortnightly", time.Now().Add(time.Hour), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Rent","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}],"cadence":%q,"start_at":%q}`,
+				a, a, b, tt.cadence, tt.startAt.Format(time.RFC3339))
+			var recurring model.RecurringExpense
+			if code := ts.do(t, a, http.MethodPost, path, body, &recurring); code != tt.status {
+				t.Fatalf("status %d, want %d", code, tt.status)
+			}
+			if tt.status == http.StatusOK && recurring.NextRunAt.Before(tt.startAt.Truncate(time.Second)) {
+				t.Errorf("next run %s is before the start %s", recurring.NextRunAt, tt.startAt)
+			}
+		})
+	}
+}
+
+func TestMaterializeRecurringExpenses(t *testing.T) {
+	ts := newTestServer(t, 2)
+	outsider := ts.addUser(t)
+	a, b := ts.users[0], ts.users[1]
+	server := controller.NewServer(ts.repos, ts.rates)
+
+	now := time.Now().UTC()
+	startAt := now.Add(-50 * time.Hour)
+	create := func(currency string, shares []model.UserShare) *model.RecurringExpense {
+		t.Helper()
+		recurring := model.RecurringExpense{
+			GroupID:     ts.groupID,
+			Amount:      1000,
+			PayerID:     a,
+			Payers:      []model.ExpensePayer{{UserID: a, Amount: 1000}},
+			Description: "Rent",
+			ExpenseType: "EQUAL",
+			Shares:      shares,
+			Currency:    currency,
+			Cadence:     "daily",
+			StartAt:     startAt,
+			NextRunAt:   startAt,
+			Active:      true,
+			CreatedBy:   a,
+		}
+		if err := ts.repos.Recurring.Create(&recurring); err != nil {
+			t.Fatalf("create recurring expense: %v", err)
+		}
+		return &recurring
+	}
+
+	rent := create("INR", []model.UserShare{{UserID: a}, {UserID: b}})
+	// Neither of these can ever be posted as they stand
+	noRate := create("EUR", []model.UserShare{{UserID: a}, {UserID: b}})
+	nonMember := create("INR", []model.UserShare{{UserID: a}, {UserID: outsider}})
+
+	for run := 0; run < 2; run++ {
+		server.MaterializeRecurringExpenses(now)
+	}
+
+	items, err := ts.repos.Items.ListByGroup(ts.groupID, repository.ItemFilter{})
+	if err != nil {
+		t.Fatalf("ListByGroup: %v", err)
+	}
+	if len(items) != 3 {
+		t.Errorf("posted %d expenses, want one for each of the 3 days rent was due", len(items))
+	}
+
+	stored, err := ts.repos.Recurring.Get(rent.ID)
+	if err != nil {
+		t.Fatalf("get recurring expense: %v", err)
+	}
+	if !stored.Active || stored.LastError != "" {
+		t.Errorf("rent was stopped: %q", stored.LastError)
+	}
+
+	for name, recurring := range map[string]*model.RecurringExpense{"no rate": noRate, "non-member": nonMember} {
+		stored, err := ts.repos.Recurring.Get(recurring.ID)
+		if err != nil {
+			t.Fatalf("get recurring expense: %v", err)
+		}
+		if stored.Active {
+			t.Errorf("%s: still active after an occurrence couldn't be posted", name)
+		}
+		if !strings.Contains(stored.LastError, startAt.Format(time.DateOnly)) {
+			t.Errorf("%s: last error %q doesn't name the failed occurrence", name, stored.LastError)
+		}
+	}
+
+	due, err := ts.repos.Recurring.Due(now)
+	if err != nil {
+		t.Fatalf("Due: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("%d recurring expenses still due", len(due))
+	}
+}
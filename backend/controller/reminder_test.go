@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-splitwise/model"
+	"go-splitwise/rates"
+	"go-splitwise/repository"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// recordingSender keeps the balances each reminder would have emailed
+type recordingSender struct {
+	sent map[string][]model.Balance
+}
+
+func (r *recordingSender) SendMonthlyBalanceReminder(recipient, userName string, balances []model.Balance) error {
+	r.sent[recipient] = balances
+	return nil
+}
+
+func TestReminderBalancesMatchHandler(t *testing.T) {
+	repos := repository.NewInMemory()
+	store := rates.NewStore(repos.Rates)
+	s := NewServer(repos, store)
+
+	var users []int64
+	emails := make(map[int64]string)
+	for i := 1; i <= 3; i++ {
+		email := fmt.Sprintf("user%d@example.com", i)
+		userID, err := repos.Users.Create(fmt.Sprintf("User %d", i), email, "hash")
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		users = append(users, userID)
+		emails[userID] = email
+	}
+	a, b, c := users[0], users[1], users[2]
+
+	var groups []int64
+	for _, name := range []string{"Flat", "Trip"} {
+		group := model.Group{GroupName: name}
+		if err := repos.Groups.Create(&group); err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+		for _, userID := range users {
+			if err := repos.Groups.AddMember(group.GroupID, userID); err != nil {
+				t.Fatalf("add member: %v", err)
+			}
+		}
+		groups = append(groups, group.GroupID)
+	}
+	flat, trip := groups[0], groups[1]
+
+	addItem := func(groupID int64, expense model.Expense, shares map[int64]int64) {
+		t.Helper()
+		expense.Description = "Expense"
+		expense.ExpenseDate = "2026-01-15"
+		expense.ExpenseType = "EXACT"
+		expense.PayerID = expense.Payers[0].UserID
+		for _, payer := range expense.Payers {
+			expense.Amount += payer.Amount
+		}
+		split := func(expense *model.Expense) error {
+			expense.Shares = nil
+			for _, userID := range users {
+				if share, ok := shares[userID]; ok {
+					expense.Shares = append(expense.Shares, model.UserShare{UserID: userID, ShareAmount: share})
+				}
+			}
+			return nil
+		}
+		if err := repos.Items.Create(groupID, &expense, split); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+	}
+
+	// Two payers in the flat, a trip expense in dollars and two repayments,
+	// one of which was reversed
+	addItem(flat, model.Expense{Payers: []model.ExpensePayer{{UserID: a, Amount: 600}, {UserID: b, Amount: 300}}},
+		map[int64]int64{a: 300, b: 0, c: -300})
+	addItem(trip, model.Expense{Currency: "USD", ExchangeRate: 80, ExchangeRateCurrency: "INR", Payers: []model.ExpensePayer{{UserID: c, Amount: 30}}},
+		map[int64]int64{a: -10, b: -10, c: 20})
+	for _, transaction := range []model.Transactions{
+		{GroupID: flat, PayerID: c, UserID: a, Amount: 100},
+		{GroupID: trip, PayerID: a, UserID: c, Amount: 800},
+	} {
+		if err := repos.Transactions.Create(&transaction); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+	}
+	reversed := model.Transactions{GroupID: flat, PayerID: c, UserID: a, Amount: 200}
+	if err := repos.Transactions.Create(&reversed); err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	if _, err := repos.Transactions.Reverse(reversed.ID, c); err != nil {
+		t.Fatalf("reverse transaction: %v", err)
+	}
+
+	sender := &recordingSender{sent: make(map[string][]model.Balance)}
+	s.newReminderService(sender).SendMonthlyBalanceReminders()
+
+	for _, userID := range users {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/users/%d/balances", userID), nil)
+		req = mux.SetURLVars(req, map[string]string{"userId": strconv.FormatInt(userID, 10)})
+		rec := httptest.NewRecorder()
+		s.GetUserBalances(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GetUserBalances for %d: status %d", userID, rec.Code)
+		}
+
+		var fromHandler model.UserBalances
+		if err := json.Unmarshal(rec.Body.Bytes(), &fromHandler); err != nil {
+			t.Fatalf("decode balances: %v", err)
+		}
+		if len(fromHandler.Groups) == 0 {
+			t.Errorf("user %d has no balances", userID)
+		}
+		if !reflect.DeepEqual(fromHandler.Groups, sender.sent[emails[userID]]) {
+			t.Errorf("user %d: handler shows %+v but the reminder sent %+v", userID, fromHandler.Groups, sender.sent[emails[userID]])
+		}
+	}
+
+	// In the flat c still owes a 300 less the 100 repaid; for the trip a and
+	// b each owe c $10 at 80, which a has paid back
+	want := map[int64]int64{a: -200, b: 800}
+	got := make(map[int64]int64)
+	for _, balance := range sender.sent[emails[c]] {
+		got[balance.OtherUserID] += balance.Amount
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("c's balances %v, want %v", got, want)
+	}
+}
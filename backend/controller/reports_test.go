@@ -0,0 +1,140 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/model"
+	"go-splitwise/rates"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// spend is what a member paid and owed in a report
+type spend struct{ paid, owed int64 }
+
+func memberSpend(members []model.MemberSpend) map[int64]spend {
+	got := make(map[int64]spend)
+	for _, member := range members {
+		got[member.UserID] = spend{member.Paid, member.Owed}
+	}
+	return got
+}
+
+func TestCategoryReport(t *testing.T) {
+	ts := newTestServer(t, 3)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	if err := ts.rates.Save([]rates.Rate{{Date: "2026-01-01", Base: "USD", Quote: "INR", Rate: 80}}); err != nil {
+		t.Fatalf("save rates: %v", err)
+	}
+	path := fmt.Sprintf("/api/groups/%d/categories", ts.groupID)
+	if code := ts.do(t, a, http.MethodPost, path, `{"name":"Pets"}`, nil); code != http.StatusCreated {
+		t.Fatalf("add category: status %d", code)
+	}
+
+	ts.addExpense(t, fmt.Sprintf(`{"amount":900,"payer_id":%d,"category":"food","expense_date":"2026-01-05","description":"Groceries","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d},{"user_id":%d}]}`, a, a, b, c))
+	ts.addExpense(t, fmt.Sprintf(`{"amount":10,"currency":"USD","payer_id":%d,"category":"travel","expense_date":"2026-01-20","description":"Tickets","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, b, b, c))
+	ts.addExpense(t, fmt.Sprintf(`{"amount":600,"payer_id":%d,"category":"Food","expense_date":"2026-02-03","description":"Dinner","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, c, a, c))
+	ts.addExpense(t, fmt.Sprintf(`{"amount":100,"payer_id":%d,"expense_date":"2026-02-10","description":"Stamps","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":100}]}`, a, b))
+	ts.addExpense(t, fmt.Sprintf(`{"amount":200,"payer_id":%d,"category":"pets","expense_date":"2026-02-15","description":"Cat food","expense_type":"EXACT","user_shares":[{"user_id":%d,"share_amount":200}]}`, b, b))
+
+	type categorySpend struct {
+		category string
+		total    int64
+		members  map[int64]spend
+	}
+	tests := []struct {
+		name       string
+		query      string
+		status     int
+		total      int64
+		categories []categorySpend
+		members    map[int64]spend
+	}{
+		{
+			name:   "all time, largest category first",
+			status: http.StatusOK,
+			total:  2600,
+			categories: []categorySpend{
+				{"food", 1500, map[int64]spend{a: {900, 600}, b: {0, 300}, c: {600, 600}}},
+				{"travel", 800, map[int64]spend{b: {800, 400}, c: {0, 400}}},
+				{"pets", 200, map[int64]spend{b: {200, 200}}},
+				{"uncategorized", 100, map[int64]spend{a: {100, 0}, b: {0, 100}}},
+			},
+			members: map[int64]spend{a: {1000, 600}, b: {1000, 1000}, c: {600, 1000}},
+		},
+		{
+			name:   "one month",
+			query:  "?from=2026-01-01&to=2026-01-31",
+			status: http.StatusOK,
+			total:  1700,
+			categories: []categorySpend{
+				{"food", 900, map[int64]spend{a: {900, 300}, b: {0, 300}, c: {0, 300}}},
+				{"travel", 800, map[int64]spend{b: {800, 400}, c: {0, 400}}},
+			},
+			members: map[int64]spend{a: {900, 300}, b: {800, 700}, c: {0, 700}},
+		},
+		{
+			name:   "a single day includes expenses on it",
+			query:  "?from=2026-01-20&to=2026-01-20",
+			status: http.StatusOK,
+			total:  800,
+			categories: []categorySpend{
+				{"travel", 800, map[int64]spend{b: {800, 400}, c: {0, 400}}},
+			},
+			members: map[int64]spend{b: {800, 400}, c: {0, 400}},
+		},
+		{
+			name:   "open-ended range",
+			query:  "?from=2026-02-01",
+			status: http.StatusOK,
+			total:  900,
+			categories: []categorySpend{
+				{"food", 600, map[int64]spend{a: {0, 300}, c: {600, 300}}},
+				{"pets", 200, map[int64]spend{b: {200, 200}}},
+				{"uncategorized", 100, map[int64]spend{a: {100, 0}, b: {0, 100}}},
+			},
+			members: map[int64]spend{a: {100, 300}, b: {200, 300}, c: {600, 300}},
+		},
+		{
+			name:       "no expenses in range",
+			query:      "?to=2025-12-31",
+			status:     http.StatusOK,
+			categories: []categorySpend{},
+			members:    map[int64]spend{},
+		},
+		{name: "invalid date", query: "?from=05-01-2026", status: http.StatusBadRequest},
+		{name: "end before start", query: "?from=2026-02-01&to=2026-01-01", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report model.CategoryReport
+			path := fmt.Sprintf("/api/groups/%d/reports/categories%s", ts.groupID, tt.query)
+			if code := ts.do(t, a, http.MethodGet, path, "", &report); code != tt.status {
+				t.Fatalf("status %d, want %d", code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			if report.Currency != rates.DefaultCurrency || report.Total != tt.total {
+				t.Errorf("total %d %s, want %d %s", report.Total, report.Currency, tt.total, rates.DefaultCurrency)
+			}
+			got := []categorySpend{}
+			for _, category := range report.Categories {
+				got = append(got, categorySpend{category.Category, category.Total, memberSpend(category.Members)})
+			}
+			if !reflect.DeepEqual(got, tt.categories) {
+				t.Errorf("categories %v, want %v", got, tt.categories)
+			}
+			if got := memberSpend(report.Members); !reflect.DeepEqual(got, tt.members) {
+				t.Errorf("members %v, want %v", got, tt.members)
+			}
+			for _, member := range report.Members {
+				if member.Name == "" {
+					t.Errorf("member %d has no name", member.UserID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package controller_test
+
+import (
+	"fmt"
+	"go-splitwise/balance"
+	"go-splitwise/controller"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"go-splitwise/router"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReverseNettingReversesWholeBatch(t *testing.T) {
+	ts := newTestServer(t, 3)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	trip := model.Group{GroupName: "Trip"}
+	if err := ts.repos.Groups.Create(&trip); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	for _, userID := range []int64{a, b} {
+		if err := ts.repos.Groups.AddMember(trip.GroupID, userID); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+
+	// b owes a 500 in the flat and a owes b 200 on the trip
+	ts.addExpense(t, fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Rent","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b))
+	path := fmt.Sprintf("/api/addExpense/%d", trip.GroupID)
+	body := fmt.Sprintf(`{"amount":400,"payer_id":%d,"description":"Train","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, b, a, b)
+	if code := ts.do(t, b, http.MethodPost, path, body, nil); code != http.StatusOK {
+		t.Fatalf("add trip expense: status %d", code)
+	}
+
+	balances := func() model.UserBalances {
+		t.Helper()
+		var balances model.UserBalances
+		if code := ts.do(t, a, http.MethodGet, fmt.Sprintf("/api/users/%d/balances", a), "", &balances); code != http.StatusOK {
+			t.Fatalf("get balances: status %d", code)
+		}
+		return balances
+	}
+	before := balances()
+
+	var netting model.NettingResult
+	if code := ts.do(t, a, http.MethodPost, fmt.Sprintf("/api/users/%d/balances/%d/net", a, b), "", &netting); code != http.StatusOK {
+		t.Fatalf("net balances: status %d", code)
+	}
+	if len(netting.Transactions) != 2 {
+		t.Fatalf("netting recorded %d transactions, want 2", len(netting.Transactions))
+	}
+	// Reverse the leg in the flat, where c is a member too
+	leg, otherLeg := netting.Transactions[0], netting.Transactions[1]
+	if leg.GroupID != ts.groupID {
+		leg, otherLeg = otherLeg, leg
+	}
+	for _, transaction := range netting.Transactions {
+		if transaction.BatchID == 0 || transaction.BatchID != leg.BatchID {
+			t.Errorf("transaction %+v is not in batch %d", transaction, leg.BatchID)
+		}
+	}
+
+	reversePath := fmt.Sprintf("/api/transactions/%d", leg.ID)
+	if code := ts.do(t, c, http.MethodDelete, reversePath, "", nil); code != http.StatusForbidden {
+		t.Errorf("reversal by someone else: status %d, want %d", code, http.StatusForbidden)
+	}
+
+	var reversed model.Transactions
+	if code := ts.do(t, b, http.MethodDelete, reversePath, "", &reversed); code != http.StatusOK {
+		t.Fatalf("reverse: status %d", code)
+	}
+	if reversed.ID != leg.ID || reversed.ReversedAt == nil {
+		t.Errorf("reversal returned %+v", reversed)
+	}
+
+	for _, transaction := range netting.Transactions {
+		stored, err := ts.repos.Transactions.Get(transaction.ID)
+		if err != nil {
+			t.Fatalf("get transaction: %v", err)
+		}
+		if stored.ReversedAt == nil || stored.ReversedBy != b {
+			t.Errorf("transaction %d in group %d was not reversed by %d", stored.ID, stored.GroupID, b)
+		}
+	}
+
+	if after := balances(); !reflect.DeepEqual(after.Groups, before.Groups) {
+		t.Errorf("balances after reversal %+v, want %+v", after.Groups, before.Groups)
+	}
+
+	otherPath := fmt.Sprintf("/api/transactions/%d", otherLeg.ID)
+	if code := ts.do(t, a, http.MethodDelete, otherPath, "", nil); code != http.StatusConflict {
+		t.Errorf("reversing the other leg again: status %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestReversePayment(t *testing.T) {
+	ts := newTestServer(t, 3)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	payment := model.Transactions{GroupID: ts.groupID, PayerID: b, UserID: a, Amount: 300}
+	if err := ts.repos.Transactions.Create(&payment); err != nil {
+		t.Fatalf("create transaction: %v", err)
+	}
+	path := fmt.Sprintf("/api/transactions/%d", payment.ID)
+
+	if code := ts.do(t, c, http.MethodDelete, path, "", nil); code != http.StatusForbidden {
+		t.Errorf("reversal by someone else: status %d, want %d", code, http.StatusForbidden)
+	}
+	stored, err := ts.repos.Transactions.Get(payment.ID)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if stored.ReversedAt != nil {
+		t.Errorf("payment was reversed by %d", stored.ReversedBy)
+	}
+
+	var reversed model.Transactions
+	if code := ts.do(t, a, http.MethodDelete, path, "", &reversed); code != http.StatusOK {
+		t.Fatalf("reversal by the receiver: status %d", code)
+	}
+	if reversed.ReversedAt == nil || reversed.ReversedBy != a {
+		t.Errorf("reversal returned %+v", reversed)
+	}
+	if code := ts.do(t, b, http.MethodDelete, path, "", nil); code != http.StatusConflict {
+		t.Errorf("reversing again: status %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestSettleUp(t *testing.T) {
+	ts := newTestServer(t, 3)
+	outsider := ts.addUser(t)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	// b owes a 500
+	ts.addExpense(t, fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Rent","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b))
+	path := fmt.Sprintf("/api/groups/%d/settle", ts.groupID)
+
+	tests := []struct {
+		name   string
+		sender int64
+		body   string
+		status int
+	}{
+		{"payer not in the group", a, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":100}`, outsider, a), http.StatusBadRequest},
+		{"receiver not in the group", b, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":100}`, b, outsider), http.StatusBadRequest},
+		{"zero amount", b, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":0}`, b, a), http.StatusBadRequest},
+		{"negative amount", b, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":-100}`, b, a), http.StatusBadRequest},
+		{"wrong direction", b, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":100}`, a, b), http.StatusBadRequest},
+		{"more than owed", b, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":600}`, b, a), http.StatusBadRequest},
+		{"paying themselves", b, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":100}`, b, b), http.StatusBadRequest},
+		{"recorded by someone else", c, fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":100}`, b, a), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := ts.do(t, tt.sender, http.MethodPost, path, tt.body, nil); code != tt.status {
+				t.Errorf("status %d, want %d", code, tt.status)
+			}
+		})
+	}
+
+	var settled model.SettleResponse
+	body := fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"settle_in_full":true}`, b, a)
+	if code := ts.do(t, b, http.MethodPost, path, body, &settled); code != http.StatusOK {
+		t.Fatalf("settle in full: status %d", code)
+	}
+	if settled.Transaction.Amount != 500 || settled.Balance != 0 {
+		t.Errorf("settled %+v, want 500 paid and nothing left", settled)
+	}
+	if code := ts.do(t, b, http.MethodPost, path, body, nil); code != http.StatusBadRequest {
+		t.Errorf("settling again: status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// Payments only go through the settle endpoint
+	old := fmt.Sprintf("/api/insertTransactions/%d", ts.groupID)
+	if code := ts.do(t, b, http.MethodPost, old, fmt.Sprintf(`{"payer_id":%d,"user_id":%d,"amount":-100}`, b, a), nil); code != http.StatusNotFound && code != http.StatusMethodNotAllowed {
+		t.Errorf("POST %s: status %d, want the route to be gone", old, code)
+	}
+}
+
+// slowDebts holds up every balance calculation after reading it, so
+// concurrent requests all read a balance before any of them records a payment
+type slowDebts struct {
+	repository.SplitRepository
+}
+
+func (s slowDebts) GroupDebts(groupID int64, baseCurrency string) ([]repository.Debt, error) {
+	debts, err := s.SplitRepository.GroupDebts(groupID, baseCurrency)
+	time.Sleep(10 * time.Millisecond)
+	return debts, err
+}
+
+func TestSettleUpConcurrently(t *testing.T) {
+	ts := newTestServer(t, 2)
+	a, b := ts.users[0], ts.users[1]
+
+	repos := ts.repos
+	repos.Splits = slowDebts{repos.Splits}
+	ts.handler = router.Router(controller.NewServer(repos, ts.rates))
+
+	// b owes a 500, so only one of the payments of 300 can go through
+	ts.addExpense(t, fmt.Sprintf(`{"amount":1000,"payer_id":%d,"description":"Rent","expense_type":"EQUAL","user_shares":[{"user_id":%d},{"user_id":%d}]}`, a, a, b))
+	path := fmt.Sprintf("/api/groups/%d/settle", ts.groupID)
+	body := fmt.Sprintf(`{"payer_id":%d,"receiver_id":%d,"amount":300}`, b, a)
+
+	const payments = 8
+	codes := make(chan int, payments)
+	var wg sync.WaitGroup
+	for i := 0; i < payments; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- ts.do(t, b, http.MethodPost, path, body, nil)
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	accepted := 0
+	for code := range codes {
+		if code == http.StatusOK {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Errorf("%d payments accepted, want 1", accepted)
+	}
+
+	owed, err := balance.NewEngine(ts.repos.Groups, ts.repos.Splits, ts.rates).Pair(ts.groupID, a, b)
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if owed != 200 {
+		t.Errorf("b owes a %d, want 200", owed)
+	}
+}
+
+func TestGetTransactions(t *testing.T) {
+	ts := newTestServer(t, 3)
+	a, b, c := ts.users[0], ts.users[1], ts.users[2]
+
+	var want []int64
+	for i := 0; i < 5; i++ {
+		payment := model.Transactions{GroupID: ts.groupID, PayerID: b, UserID: a, Amount: int64(100 * (i + 1))}
+		if i%2 == 1 {
+			payment.PayerID, payment.UserID = c, b
+		}
+		if err := ts.repos.Transactions.Create(&payment); err != nil {
+			t.Fatalf("create transaction: %v", err)
+		}
+		want = append([]int64{payment.ID}, want...)
+	}
+	path := fmt.Sprintf("/api/getTransactions/%d", ts.groupID)
+
+	t.Run("pages", func(t *testing.T) {
+		var got []int64
+		cursor := ""
+		for page := 0; ; page++ {
+			if page > len(want) {
+				t.Fatal("paging doesn't end")
+			}
+			query := "?limit=2"
+			if cursor != "" {
+				query += "&cursor=" + cursor
+			}
+			var result model.TransactionPage
+			if code := ts.do(t, a, http.MethodGet, path+query, "", &result); code != http.StatusOK {
+				t.Fatalf("page %d: status %d", page, code)
+			}
+			if result.Currency != "INR" {
+				t.Errorf("page %d is in %q, want INR", page, result.Currency)
+			}
+			for _, transaction := range result.Transactions {
+				if transaction.Kind != model.TransactionPayment || transaction.CreatedAt.IsZero() {
+					t.Errorf("transaction %+v isn't a dated payment", transaction)
+				}
+				got = append(got, transaction.ID)
+			}
+			if result.NextCursor == "" {
+				break
+			}
+			cursor = result.NextCursor
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("pages hold transactions %v, want %v", got, want)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		var result model.TransactionPage
+		query := fmt.Sprintf("?payer_id=%d&receiver_id=%d&from=%s", c, b, time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly))
+		if code := ts.do(t, a, http.MethodGet, path+query, "", &result); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		if len(result.Transactions) != 2 || result.NextCursor != "" {
+			t.Errorf("got %d transactions and cursor %q, want the 2 from c to b on one page", len(result.Transactions), result.NextCursor)
+		}
+		for _, transaction := range result.Transactions {
+			if transaction.PayerID != c || transaction.UserID != b {
+				t.Errorf("transaction %+v doesn't match the filter", transaction)
+			}
+		}
+	})
+
+	t.Run("empty page", func(t *testing.T) {
+		var result model.TransactionPage
+		if code := ts.do(t, a, http.MethodGet, path+"?to=2020-01-01", "", &result); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		if result.Transactions == nil || len(result.Transactions) != 0 || result.NextCursor != "" {
+			t.Errorf("got %+v, want an empty list without a cursor", result)
+		}
+	})
+
+	for _, query := range []string{
+		"?limit=0",
+		"?limit=201",
+		"?limit=ten",
+		"?cursor=not-a-cursor",
+		"?payer_id=b",
+		"?from=2026-13-01",
+		"?from=2026-02-01&to=2026-01-01",
+	} {
+		if code := ts.do(t, a, http.MethodGet, path+query, "", nil); code != http.StatusBadRequest {
+			t.Errorf("GET %s: status %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+}
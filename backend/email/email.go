@@ -169,6 +169,14 @@ func (s *EmailService) SendMonthlyBalanceReminder(recipient, userName string, ba
 	return nil
 }
 
+// Helper function to prefix an amount with its currency code
+func formatAmount(amount int64, currency string) string {
+	if currency == "" {
+		return fmt.Sprintf("%d", amount)
+	}
+	return fmt.Sprintf("%s %d", currency, amount)
+}
+
 // Helper function to format balances by group for HTML email
 func formatBalancesByGroup(balances []model.Balance) string {
 	// Group balances by group name
@@ -191,10 +199,10 @@ func formatBalancesByGroup(balances []model.Balance) string {
 			var amountClass, amountText string
 			if balance.Amount > 0 {
 				amountClass = "owed"
-				amountText = fmt.Sprintf("You are owed %d", balance.Amount)
+				amountText = fmt.Sprintf("You are owed %s", formatAmount(balance.Amount, balance.Currency))
 			} else if balance.Amount < 0 {
 				amountClass = "owing"
-				amountText = fmt.Sprintf("You owe %d", -balance.Amount)
+				amountText = fmt.Sprintf("You owe %s", formatAmount(-balance.Amount, balance.Currency))
 			} else {
 				amountClass = "settled"
 				amountText = "You're settled up"
@@ -231,11 +239,11 @@ func formatBalancesByGroupText(balances []model.Balance) string {
 
 		for _, balance := range groupBalances {
 			if balance.Amount > 0 {
-				textContent += fmt.Sprintf("With %s: You are owed %d\n",
-					balance.OtherUserName, balance.Amount)
+				textContent += fmt.Sprintf("With %s: You are owed %s\n",
+					balance.OtherUserName, formatAmount(balance.Amount, balance.Currency))
 			} else if balance.Amount < 0 {
-				textContent += fmt.Sprintf("With %s: You owe %d\n",
-					balance.OtherUserName, -balance.Amount)
+				textContent += fmt.Sprintf("With %s: You owe %s\n",
+					balance.OtherUserName, formatAmount(-balance.Amount, balance.Currency))
 			} else {
 				textContent += fmt.Sprintf("With %s: You're settled up\n",
 					balance.OtherUserName)
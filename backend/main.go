@@ -1,22 +1,62 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"go-splitwise/controller"
+	"go-splitwise/migrations"
+	"go-splitwise/rates"
+	"go-splitwise/repository"
 	"go-splitwise/router"
 
-	_ "github.com/lib/pq"
+	"github.com/joho/godotenv"
 	"github.com/rs/cors"
 )
 
 func main() {
 	fmt.Println("Hello World")
 
+	if err := godotenv.Load(); err != nil {
+		log.Println("Error loading .env file, using existing environment variables")
+	}
+
+	db, dialect, err := repository.Open(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatalf("Database connection failed: %v", err)
+	}
+	fmt.Println("Successfully connected!")
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, string(dialect), os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	applied, err := migrations.Up(db, string(dialect))
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %d_%s\n", migration.Version, migration.Name)
+	}
+
+	repos := repository.NewSQL(db)
+	exchangeRates := rates.NewStore(repos.Rates)
+	if ratesFile := os.Getenv("EXCHANGE_RATES_FILE"); ratesFile != "" {
+		if err := exchangeRates.LoadFile(ratesFile); err != nil {
+			log.Printf("Error loading exchange rates from %s: %v", ratesFile, err)
+		}
+	}
+
+	server := controller.NewServer(repos, exchangeRates)
+
 	// Set up CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "https://go-splitwise.vercel.app"},
@@ -25,14 +65,24 @@ func main() {
 		AllowCredentials: true,
 	})
 
-	r := router.Router()
+	r := router.Router(server)
 
 	handler := c.Handler(r)
 
 	ticker := time.NewTicker(24 * time.Hour)
 	go func() {
 		for range ticker.C {
-			controller.CleanupExpiredSessions()
+			server.CleanupExpiredSessions()
+		}
+	}()
+
+	// Post recurring expenses as they fall due, starting with any that came
+	// due while the server was down
+	recurringTicker := time.NewTicker(time.Minute)
+	go func() {
+		server.MaterializeRecurringExpenses(time.Now())
+		for now := range recurringTicker.C {
+			server.MaterializeRecurringExpenses(now)
 		}
 	}()
 
@@ -44,3 +94,43 @@ func main() {
 	fmt.Println("Listening at port 4000...")
 	log.Fatal(http.ListenAndServe(":"+port, handler))
 }
+
+// runMigrate handles `migrate [up | down [steps] | version]`
+func runMigrate(db *sql.DB, dialect string, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(db, dialect)
+		for _, migration := range applied {
+			fmt.Printf("Applied migration %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, dialect, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted migration %d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "version":
+		version, err := migrations.Version(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Schema version %d\n", version)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or version", command)
+	}
+}
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS reminder_logs;
+DROP TABLE IF EXISTS reminder_jobs;
+DROP TABLE IF EXISTS memories;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS item_splits;
+DROP TABLE IF EXISTS items;
+DROP TABLE IF EXISTS group_users;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,91 @@
+CREATE TABLE IF NOT EXISTS users (
+    user_id   BIGSERIAL PRIMARY KEY,
+    name      TEXT NOT NULL,
+    email     TEXT NOT NULL UNIQUE,
+    password  TEXT,
+    google_id TEXT UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+    group_id BIGSERIAL PRIMARY KEY,
+    name     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_users (
+    group_id BIGINT NOT NULL REFERENCES groups (group_id),
+    user_id  BIGINT NOT NULL REFERENCES users (user_id),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS items (
+    item_id     BIGSERIAL PRIMARY KEY,
+    group_id    BIGINT NOT NULL REFERENCES groups (group_id),
+    amount      BIGINT NOT NULL,
+    paid_by     BIGINT NOT NULL REFERENCES users (user_id),
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS items_group_id_idx ON items (group_id);
+
+CREATE TABLE IF NOT EXISTS item_splits (
+    item_id BIGINT NOT NULL REFERENCES items (item_id),
+    user_id BIGINT NOT NULL REFERENCES users (user_id),
+    share   BIGINT NOT NULL,
+    PRIMARY KEY (item_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (user_id),
+    payer_id   BIGINT NOT NULL REFERENCES users (user_id),
+    group_id   BIGINT NOT NULL REFERENCES groups (group_id),
+    amount     BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS transactions_group_id_idx ON transactions (group_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id TEXT PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (user_id),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (user_id),
+    email      TEXT NOT NULL,
+    code       TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used       BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_email_idx ON password_resets (email);
+
+CREATE TABLE IF NOT EXISTS memories (
+    id         SERIAL PRIMARY KEY,
+    group_id   BIGINT NOT NULL REFERENCES groups (group_id),
+    filename   TEXT NOT NULL,
+    image_url  TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS reminder_jobs (
+    id            BIGSERIAL PRIMARY KEY,
+    job_type      TEXT NOT NULL,
+    status        TEXT NOT NULL,
+    error         TEXT,
+    success_count INTEGER NOT NULL DEFAULT 0,
+    error_count   INTEGER NOT NULL DEFAULT 0,
+    started_at    TIMESTAMPTZ NOT NULL,
+    completed_at  TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS reminder_logs (
+    id            BIGSERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL REFERENCES users (user_id),
+    reminder_type TEXT NOT NULL,
+    sent_at       TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,6 @@
+ALTER TABLE item_splits DROP COLUMN IF EXISTS rounding_adjustment;
+
+ALTER TABLE items DROP COLUMN IF EXISTS rounding_remainder;
+ALTER TABLE items DROP COLUMN IF EXISTS rounding_policy;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS rounding_policy;
@@ -0,0 +1,6 @@
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS rounding_policy TEXT;
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS rounding_policy TEXT;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS rounding_remainder BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE item_splits ADD COLUMN IF NOT EXISTS rounding_adjustment BIGINT NOT NULL DEFAULT 0;
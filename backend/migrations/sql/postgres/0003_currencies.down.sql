@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE items DROP COLUMN IF EXISTS exchange_rate_currency;
+ALTER TABLE items DROP COLUMN IF EXISTS exchange_rate;
+ALTER TABLE items DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE groups DROP COLUMN IF EXISTS base_currency;
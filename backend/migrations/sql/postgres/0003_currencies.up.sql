@@ -0,0 +1,13 @@
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS base_currency TEXT NOT NULL DEFAULT 'INR';
+
+ALTER TABLE items ADD COLUMN IF NOT EXISTS currency TEXT;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS exchange_rate DOUBLE PRECISION;
+ALTER TABLE items ADD COLUMN IF NOT EXISTS exchange_rate_currency TEXT;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    rate_date      DATE NOT NULL,
+    base_currency  TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate           DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (rate_date, base_currency, quote_currency)
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS item_payers (
+    item_id BIGINT NOT NULL REFERENCES items (item_id),
+    user_id BIGINT NOT NULL REFERENCES users (user_id),
+    amount  BIGINT NOT NULL,
+    PRIMARY KEY (item_id, user_id)
+);
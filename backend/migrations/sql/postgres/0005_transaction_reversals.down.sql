@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_by;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_at;
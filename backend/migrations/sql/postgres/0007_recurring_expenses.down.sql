@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recurring_expense_runs;
+DROP TABLE IF EXISTS recurring_expenses;
@@ -0,0 +1,12 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+ALTER TABLE recurring_expenses ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '';
+
+-- Categories a group added on top of the built-in ones
+CREATE TABLE IF NOT EXISTS group_categories (
+    group_id   BIGINT NOT NULL REFERENCES groups (group_id),
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS items_group_category_idx ON items (group_id, category);
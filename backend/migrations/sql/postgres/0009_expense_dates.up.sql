@@ -0,0 +1,8 @@
+-- The day an expense happened, which may be earlier than when it was
+-- recorded; existing items take the day they were created
+ALTER TABLE items ADD COLUMN IF NOT EXISTS expense_date DATE;
+UPDATE items SET expense_date = CAST(created_at AS DATE) WHERE expense_date IS NULL;
+ALTER TABLE items ALTER COLUMN expense_date SET NOT NULL;
+
+-- Expenses are listed a page at a time in (expense_date, item_id) order
+CREATE INDEX IF NOT EXISTS items_group_expense_date_item_idx ON items (group_id, expense_date, item_id);
@@ -0,0 +1,91 @@
+CREATE TABLE IF NOT EXISTS users (
+    user_id   INTEGER PRIMARY KEY AUTOINCREMENT,
+    name      TEXT NOT NULL,
+    email     TEXT NOT NULL UNIQUE,
+    password  TEXT,
+    google_id TEXT UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+    group_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name     TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS group_users (
+    group_id INTEGER NOT NULL REFERENCES groups (group_id),
+    user_id  INTEGER NOT NULL REFERENCES users (user_id),
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS items (
+    item_id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id    INTEGER NOT NULL REFERENCES groups (group_id),
+    amount      INTEGER NOT NULL,
+    paid_by     INTEGER NOT NULL REFERENCES users (user_id),
+    description TEXT NOT NULL DEFAULT '',
+    created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS items_group_id_idx ON items (group_id);
+
+CREATE TABLE IF NOT EXISTS item_splits (
+    item_id INTEGER NOT NULL REFERENCES items (item_id),
+    user_id INTEGER NOT NULL REFERENCES users (user_id),
+    share   INTEGER NOT NULL,
+    PRIMARY KEY (item_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (user_id),
+    payer_id   INTEGER NOT NULL REFERENCES users (user_id),
+    group_id   INTEGER NOT NULL REFERENCES groups (group_id),
+    amount     INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS transactions_group_id_idx ON transactions (group_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id TEXT PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (user_id),
+    expires_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER NOT NULL REFERENCES users (user_id),
+    email      TEXT NOT NULL,
+    code       TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used       BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_email_idx ON password_resets (email);
+
+CREATE TABLE IF NOT EXISTS memories (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id   INTEGER NOT NULL REFERENCES groups (group_id),
+    filename   TEXT NOT NULL,
+    image_url  TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reminder_jobs (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_type      TEXT NOT NULL,
+    status        TEXT NOT NULL,
+    error         TEXT,
+    success_count INTEGER NOT NULL DEFAULT 0,
+    error_count   INTEGER NOT NULL DEFAULT 0,
+    started_at    DATETIME NOT NULL,
+    completed_at  DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS reminder_logs (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       INTEGER NOT NULL REFERENCES users (user_id),
+    reminder_type TEXT NOT NULL,
+    sent_at       DATETIME NOT NULL
+);
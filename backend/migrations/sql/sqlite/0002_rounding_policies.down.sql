@@ -0,0 +1,6 @@
+ALTER TABLE item_splits DROP COLUMN rounding_adjustment;
+
+ALTER TABLE items DROP COLUMN rounding_remainder;
+ALTER TABLE items DROP COLUMN rounding_policy;
+
+ALTER TABLE groups DROP COLUMN rounding_policy;
@@ -0,0 +1,6 @@
+ALTER TABLE groups ADD COLUMN rounding_policy TEXT;
+
+ALTER TABLE items ADD COLUMN rounding_policy TEXT;
+ALTER TABLE items ADD COLUMN rounding_remainder INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE item_splits ADD COLUMN rounding_adjustment INTEGER NOT NULL DEFAULT 0;
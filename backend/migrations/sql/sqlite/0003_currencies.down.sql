@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE items DROP COLUMN exchange_rate_currency;
+ALTER TABLE items DROP COLUMN exchange_rate;
+ALTER TABLE items DROP COLUMN currency;
+
+ALTER TABLE groups DROP COLUMN base_currency;
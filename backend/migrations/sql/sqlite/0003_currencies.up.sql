@@ -0,0 +1,13 @@
+ALTER TABLE groups ADD COLUMN base_currency TEXT NOT NULL DEFAULT 'INR';
+
+ALTER TABLE items ADD COLUMN currency TEXT;
+ALTER TABLE items ADD COLUMN exchange_rate REAL;
+ALTER TABLE items ADD COLUMN exchange_rate_currency TEXT;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    rate_date      TEXT NOT NULL,
+    base_currency  TEXT NOT NULL,
+    quote_currency TEXT NOT NULL,
+    rate           REAL NOT NULL,
+    PRIMARY KEY (rate_date, base_currency, quote_currency)
+);
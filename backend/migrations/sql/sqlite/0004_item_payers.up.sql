@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS item_payers (
+    item_id INTEGER NOT NULL REFERENCES items (item_id),
+    user_id INTEGER NOT NULL REFERENCES users (user_id),
+    amount  INTEGER NOT NULL,
+    PRIMARY KEY (item_id, user_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN reversed_by;
+ALTER TABLE transactions DROP COLUMN reversed_at;
@@ -0,0 +1,2 @@
+ALTER TABLE transactions ADD COLUMN reversed_at DATETIME;
+ALTER TABLE transactions ADD COLUMN reversed_by INTEGER REFERENCES users (user_id);
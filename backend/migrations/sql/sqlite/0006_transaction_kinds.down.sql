@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS transactions_batch_id_idx;
+ALTER TABLE transactions DROP COLUMN batch_id;
+ALTER TABLE transactions DROP COLUMN kind;
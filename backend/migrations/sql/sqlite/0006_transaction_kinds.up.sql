@@ -0,0 +1,6 @@
+ALTER TABLE transactions ADD COLUMN kind TEXT NOT NULL DEFAULT 'payment';
+
+-- Transactions recorded together, like the legs of a netting, share the ID of
+-- the first one so they can be audited and reversed as a unit
+ALTER TABLE transactions ADD COLUMN batch_id INTEGER;
+CREATE INDEX IF NOT EXISTS transactions_batch_id_idx ON transactions (batch_id);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_id     INTEGER NOT NULL REFERENCES groups (group_id),
+    amount       INTEGER NOT NULL,
+    payer_id     INTEGER NOT NULL REFERENCES users (user_id),
+    payers       TEXT NOT NULL DEFAULT '[]',
+    description  TEXT NOT NULL DEFAULT '',
+    expense_type TEXT NOT NULL,
+    shares       TEXT NOT NULL,
+    currency     TEXT NOT NULL,
+    cadence      TEXT NOT NULL,
+    start_at     DATETIME NOT NULL,
+    next_run_at  DATETIME NOT NULL,
+    last_run_at  DATETIME,
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    last_error   TEXT NOT NULL DEFAULT '',
+    created_by   INTEGER NOT NULL REFERENCES users (user_id),
+    created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recurring_expenses_due_idx ON recurring_expenses (next_run_at) WHERE active;
+
+-- One row per occurrence; the primary key stops an occurrence from being
+-- posted twice
+CREATE TABLE IF NOT EXISTS recurring_expense_runs (
+    recurring_expense_id INTEGER NOT NULL REFERENCES recurring_expenses (id),
+    scheduled_for        DATETIME NOT NULL,
+    item_id              INTEGER REFERENCES items (item_id) ON DELETE SET NULL,
+    PRIMARY KEY (recurring_expense_id, scheduled_for)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS items_group_category_idx;
+DROP TABLE IF EXISTS group_categories;
+ALTER TABLE recurring_expenses DROP COLUMN category;
+ALTER TABLE items DROP COLUMN category;
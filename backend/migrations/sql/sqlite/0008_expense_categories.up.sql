@@ -0,0 +1,12 @@
+ALTER TABLE items ADD COLUMN category TEXT NOT NULL DEFAULT '';
+ALTER TABLE recurring_expenses ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+-- Categories a group added on top of the built-in ones
+CREATE TABLE IF NOT EXISTS group_categories (
+    group_id   INTEGER NOT NULL REFERENCES groups (group_id),
+    name       TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS items_group_category_idx ON items (group_id, category);
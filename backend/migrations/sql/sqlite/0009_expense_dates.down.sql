@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS items_group_expense_date_item_idx;
+ALTER TABLE items DROP COLUMN expense_date;
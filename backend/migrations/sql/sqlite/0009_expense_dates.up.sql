@@ -0,0 +1,7 @@
+-- The day an expense happened, which may be earlier than when it was
+-- recorded; existing items take the day they were created
+ALTER TABLE items ADD COLUMN expense_date DATE;
+UPDATE items SET expense_date = date(created_at) WHERE expense_date IS NULL;
+
+-- Expenses are listed a page at a time in (expense_date, item_id) order
+CREATE INDEX IF NOT EXISTS items_group_expense_date_item_idx ON items (group_id, expense_date, item_id);
@@ -20,27 +20,113 @@ type UserResponse struct {
 }
 
 type Group struct {
-	GroupID   int64  `json:"group_id"`
-	GroupName string `json:"group_name"`
+	GroupID        int64  `json:"group_id"`
+	GroupName      string `json:"group_name"`
+	RoundingPolicy string `json:"rounding_policy,omitempty"`
+	BaseCurrency   string `json:"base_currency,omitempty"`
 }
 
 type GroupUsers struct {
 	UserIDs []int64 `json:"user_ids"`
 }
 
+// UserShare is a user's part of an expense. On input ShareAmount holds an
+// amount, a percentage or a weight depending on the expense type; on output it
+// holds the user's balance for the item.
 type UserShare struct {
-	UserID      int64 `json:"user_id"`
-	ShareAmount int64 `json:"share_amount"`
+	UserID             int64 `json:"user_id"`
+	ShareAmount        int64 `json:"share_amount"`
+	RoundingAdjustment int64 `json:"rounding_adjustment,omitempty"`
+}
+
+type ExpensePayer struct {
+	UserID int64 `json:"user_id"`
+	Amount int64 `json:"amount"`
 }
 
 type Expense struct {
-	ExpenseID   int64       `json:"expense_id"`
-	Amount      int64       `json:"amount"`
-	PayerID     int64       `json:"payer_id"`
-	Description string      `json:"description"`
-	ExpenseType string      `json:"expense_type"`
-	Shares      []UserShare `json:"user_shares"`
-	Created_at  string      `json:"date"`
+	ExpenseID            int64          `json:"expense_id"`
+	GroupID              int64          `json:"group_id,omitempty"`
+	Amount               int64          `json:"amount"`
+	PayerID              int64          `json:"payer_id"`
+	Payers               []ExpensePayer `json:"payers,omitempty"`
+	Description          string         `json:"description"`
+	Category             string         `json:"category"`
+	ExpenseType          string         `json:"expense_type"`
+	Shares               []UserShare    `json:"user_shares"`
+	Currency             string         `json:"currency,omitempty"`
+	ExchangeRate         float64        `json:"exchange_rate,omitempty"`
+	ExchangeRateCurrency string         `json:"exchange_rate_currency,omitempty"`
+	Created_at           string         `json:"date"`
+	ExpenseDate          string         `json:"expense_date"`
+	RoundingPolicy       string         `json:"rounding_policy,omitempty"`
+	RoundingRemainder    int64          `json:"rounding_remainder"`
+}
+
+// ExpensePage is one page of a group's expenses. NextCursor fetches the next
+// page and is empty on the last one.
+type ExpensePage struct {
+	Items      []Expense `json:"items"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// RecurringExpense is an expense that is posted to its group automatically
+// on every occurrence of Cadence: daily, weekly, monthly or a cron expression.
+// LastError says why it was stopped when an occurrence couldn't be posted.
+type RecurringExpense struct {
+	ID          int64          `json:"id"`
+	GroupID     int64          `json:"group_id"`
+	Amount      int64          `json:"amount"`
+	PayerID     int64          `json:"payer_id"`
+	Payers      []ExpensePayer `json:"payers,omitempty"`
+	Description string         `json:"description"`
+	Category    string         `json:"category"`
+	ExpenseType string         `json:"expense_type"`
+	Shares      []UserShare    `json:"user_shares"`
+	Currency    string         `json:"currency,omitempty"`
+	Cadence     string         `json:"cadence"`
+	StartAt     time.Time      `json:"start_at"`
+	NextRunAt   time.Time      `json:"next_run_at"`
+	LastRunAt   *time.Time     `json:"last_run_at,omitempty"`
+	Active      bool           `json:"active"`
+	LastError   string         `json:"last_error,omitempty"`
+	CreatedBy   int64          `json:"created_by"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Category is a label for expenses, either built in or added by a group
+type Category struct {
+	Name   string `json:"name"`
+	Custom bool   `json:"custom"`
+}
+
+// MemberSpend is what a member paid towards a group's expenses and what their
+// own share of those expenses came to
+type MemberSpend struct {
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+	Paid   int64  `json:"paid"`
+	Owed   int64  `json:"owed"`
+}
+
+// CategorySpend is the total spent on one category along with each member's
+// part of it
+type CategorySpend struct {
+	Category string        `json:"category"`
+	Total    int64         `json:"total"`
+	Members  []MemberSpend `json:"members"`
+}
+
+// CategoryReport breaks a group's spending down by category and member, in the
+// group's base currency. From and To are empty when the range is open.
+type CategoryReport struct {
+	GroupID    int64           `json:"group_id"`
+	Currency   string          `json:"currency"`
+	From       string          `json:"from,omitempty"`
+	To         string          `json:"to,omitempty"`
+	Total      int64           `json:"total"`
+	Categories []CategorySpend `json:"categories"`
+	Members    []MemberSpend   `json:"members"`
 }
 
 type UserIDsInput struct {
@@ -82,18 +168,95 @@ type MemoryResponse struct {
 	Memories []Memory `json:"memories,omitempty"`
 }
 
+// Kinds of transactions
+const (
+	// TransactionPayment is money that actually changed hands
+	TransactionPayment = "payment"
+	// TransactionNetting offsets a debt against one in another group
+	TransactionNetting = "netting"
+)
+
+// Transactions is a payment from PayerID to UserID. Reversed payments are
+// kept for the record but no longer count towards balances. Transactions
+// recorded together, like the legs of a netting, share the ID of the first
+// one as their BatchID.
 type Transactions struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	PayerID   int64     `json:"payer_id"`
-	GroupID   int64     `json:"group_id"`
-	Amount    int64     `json:"amount"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	PayerID    int64      `json:"payer_id"`
+	GroupID    int64      `json:"group_id"`
+	Amount     int64      `json:"amount"`
+	Kind       string     `json:"kind"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReversedAt *time.Time `json:"reversed_at,omitempty"`
+	ReversedBy int64      `json:"reversed_by,omitempty"`
+	BatchID    int64      `json:"batch_id,omitempty"`
+}
+
+// TransactionPage is one page of a group's transactions, whose amounts are in
+// Currency, the group's base currency. NextCursor fetches the next page and is
+// empty on the last one.
+type TransactionPage struct {
+	Transactions []Transactions `json:"transactions"`
+	Currency     string         `json:"currency"`
+	NextCursor   string         `json:"next_cursor,omitempty"`
 }
 
+// Balance is what OtherUserID owes a user, negative when the user owes. It
+// covers a single group when GroupID is set and all shared groups otherwise.
 type Balance struct {
-	OtherUserID   int64
-	OtherUserName string
-	Amount        int64
-	GroupName     string
+	OtherUserID   int64  `json:"other_user_id"`
+	OtherUserName string `json:"other_user_name"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	GroupID       int64  `json:"group_id,omitempty"`
+	GroupName     string `json:"group_name,omitempty"`
+}
+
+// NettingResult is the outcome of netting two users' balances across their
+// shared groups: the offsetting transactions recorded and, per currency, the
+// balance left to settle and the group it now sits in
+type NettingResult struct {
+	Transactions []Transactions `json:"transactions"`
+	Balances     []Balance      `json:"balances"`
+}
+
+// UserBalances is a user's balance with each other person across all shared
+// groups, one per currency, along with the per-group balances behind them
+type UserBalances struct {
+	UserID int64     `json:"user_id"`
+	Totals []Balance `json:"totals"`
+	Groups []Balance `json:"groups"`
+}
+
+type Transfer struct {
+	FromUserID int64  `json:"from_user_id"`
+	ToUserID   int64  `json:"to_user_id"`
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency,omitempty"`
+}
+
+type SettlePlan struct {
+	GroupID   int64       `json:"group_id"`
+	Currency  string      `json:"currency"`
+	Balances  []UserShare `json:"balances"`
+	Transfers []Transfer  `json:"transfers"`
+}
+
+// SettleRequest records PayerID paying ReceiverID back. With SettleInFull the
+// amount is whatever PayerID currently owes.
+type SettleRequest struct {
+	PayerID          int64 `json:"payer_id"`
+	ReceiverID       int64 `json:"receiver_id"`
+	Amount           int64 `json:"amount"`
+	SettleInFull     bool  `json:"settle_in_full,omitempty"`
+	AllowOverpayment bool  `json:"allow_overpayment,omitempty"`
+}
+
+// SettleResponse is a recorded payment along with what the payer still owes
+// the receiver, negative when the receiver now owes the payer
+type SettleResponse struct {
+	Transaction Transactions `json:"transaction"`
+	Balance     int64        `json:"balance"`
+	Currency    string       `json:"currency"`
 }
@@ -0,0 +1,249 @@
+package rates
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-splitwise/repository"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the base currency of groups that never chose one
+const DefaultCurrency = "INR"
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+var currencyCodeRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Rate is the value of one unit of Base expressed in Quote on Date
+type Rate struct {
+	Date  string  `json:"date,omitempty"`
+	Base  string  `json:"base"`
+	Quote string  `json:"quote"`
+	Rate  float64 `json:"rate"`
+}
+
+// Snapshot is a set of rates published for a single day
+type Snapshot struct {
+	Date  string `json:"date"`
+	Rates []Rate `json:"rates"`
+}
+
+// Store validates, looks up and converts exchange rates kept in a repository
+type Store struct {
+	repo repository.RateRepository
+}
+
+// NewStore creates a rate store backed by repo
+func NewStore(repo repository.RateRepository) *Store {
+	return &Store{repo: repo}
+}
+
+// NormalizeCode upper-cases an ISO 4217 currency code and validates its shape
+func NormalizeCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !currencyCodeRegex.MatchString(code) {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	return code, nil
+}
+
+// Validate normalizes r, defaulting its date to today, and checks the rate is
+// usable
+func (r *Rate) Validate() error {
+	var err error
+	if r.Base, err = NormalizeCode(r.Base); err != nil {
+		return err
+	}
+	if r.Quote, err = NormalizeCode(r.Quote); err != nil {
+		return err
+	}
+	if r.Base == r.Quote {
+		return fmt.Errorf("rate %s/%s converts a currency to itself", r.Base, r.Quote)
+	}
+	if r.Rate <= 0 || math.IsInf(r.Rate, 0) || math.IsNaN(r.Rate) {
+		return fmt.Errorf("rate %s/%s must be a positive number", r.Base, r.Quote)
+	}
+
+	r.Date = strings.TrimSpace(r.Date)
+	if r.Date == "" {
+		r.Date = time.Now().UTC().Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, r.Date); err != nil {
+		return fmt.Errorf("invalid rate date %q, expected YYYY-MM-DD", r.Date)
+	}
+	return nil
+}
+
+// ParseJSON reads rates from either a snapshot object or a list of rates.
+// Rates without a date take the snapshot's date.
+func ParseJSON(data []byte) ([]Rate, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var rates []Rate
+		if err := json.Unmarshal(data, &rates); err != nil {
+			return nil, fmt.Errorf("failed to parse rates: %w", err)
+		}
+		return rates, nil
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse rates: %w", err)
+	}
+	for i := range snapshot.Rates {
+		if snapshot.Rates[i].Date == "" {
+			snapshot.Rates[i].Date = snapshot.Date
+		}
+	}
+	return snapshot.Rates, nil
+}
+
+// ParseCSV reads rates from date,base,quote,rate rows. A header row is
+// skipped when present.
+func ParseCSV(r io.Reader) ([]Rate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rates: %w", err)
+	}
+
+	var rates []Rate
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate on line %d: %q", i+1, record[3])
+		}
+		rates = append(rates, Rate{
+			Date:  record[0],
+			Base:  record[1],
+			Quote: record[2],
+			Rate:  value,
+		})
+	}
+	return rates, nil
+}
+
+// Save stores rates under their dates, replacing values already stored for
+// the same day and pair
+func (s *Store) Save(rates []Rate) error {
+	for i := range rates {
+		if err := rates[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	stored := make([]repository.ExchangeRate, len(rates))
+	for i, rate := range rates {
+		stored[i] = repository.ExchangeRate{Date: rate.Date, Base: rate.Base, Quote: rate.Quote, Rate: rate.Rate}
+	}
+	return s.repo.Save(stored)
+}
+
+// LoadFile saves the rates in a JSON or CSV file so conversion works without
+// network access
+func (s *Store) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read rates file: %w", err)
+	}
+
+	var rates []Rate
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		rates, err = ParseCSV(bytes.NewReader(data))
+	} else {
+		rates, err = ParseJSON(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.Save(rates)
+}
+
+// On returns the rate converting from into to that was in effect on date,
+// i.e. the latest one published on or before it. The inverse of the
+// opposite pair is used when only that one is stored.
+func (s *Store) On(date time.Time, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	rate, err := s.repo.Find(date.Format(time.DateOnly), from, to)
+	if errors.Is(err, repository.ErrNotFound) {
+		return 0, fmt.Errorf("%w: %s/%s on %s", ErrRateNotFound, from, to, date.Format(time.DateOnly))
+	} else if err != nil {
+		return 0, err
+	}
+
+	if rate.Base != from {
+		return 1 / rate.Rate, nil
+	}
+	return rate.Rate, nil
+}
+
+// Latest returns the most recent rate converting from into to
+func (s *Store) Latest(from, to string) (float64, error) {
+	return s.On(time.Now().UTC(), from, to)
+}
+
+// Convert applies rate to amount, rounding to the nearest unit
+func Convert(amount int64, rate float64) int64 {
+	return int64(math.Round(float64(amount) * rate))
+}
+
+// Converter converts amounts into one target currency, caching the rates it
+// has already looked up
+type Converter struct {
+	store  *Store
+	target string
+	cache  map[string]float64
+}
+
+// NewConverter creates a converter into the target currency
+func (s *Store) NewConverter(target string) *Converter {
+	return &Converter{
+		store:  s,
+		target: target,
+		cache:  make(map[string]float64),
+	}
+}
+
+// Convert converts amount from currency into the converter's target currency.
+// An empty currency is taken to already be in the target currency. A rate
+// pinned into pinnedCurrency is used instead of the latest one when it
+// matches the target.
+func (c *Converter) Convert(amount int64, currency string, pinnedRate float64, pinnedCurrency string) (int64, error) {
+	if currency == "" || currency == c.target {
+		return amount, nil
+	}
+	if pinnedRate > 0 && pinnedCurrency == c.target {
+		return Convert(amount, pinnedRate), nil
+	}
+
+	rate, ok := c.cache[currency]
+	if !ok {
+		var err error
+		rate, err = c.store.Latest(currency, c.target)
+		if err != nil {
+			return 0, err
+		}
+		c.cache[currency] = rate
+	}
+
+	return Convert(amount, rate), nil
+}
@@ -0,0 +1,118 @@
+package repository_test
+
+import (
+	"go-splitwise/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCategorySpend(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 3)
+
+			items := []struct {
+				expense  model.Expense
+				balances []int64
+			}{
+				{model.Expense{Amount: 900, Category: "food", ExpenseDate: "2026-01-05",
+					Payers: []model.ExpensePayer{{UserID: users[0], Amount: 900}}},
+					[]int64{600, -300, -300}},
+				// Two payers
+				{model.Expense{Amount: 600, Category: "food", ExpenseDate: "2026-01-31",
+					Payers: []model.ExpensePayer{{UserID: users[1], Amount: 400}, {UserID: users[2], Amount: 200}}},
+					[]int64{-200, 200, 0}},
+				// Pinned to the base currency, so converted
+				{model.Expense{Amount: 10, Currency: "USD", ExchangeRate: 80, ExchangeRateCurrency: "INR", ExpenseDate: "2026-01-20",
+					Category: "travel", Payers: []model.ExpensePayer{{UserID: users[1], Amount: 10}}},
+					[]int64{0, 5, -5}},
+				// Pinned to another currency, so left unconverted
+				{model.Expense{Amount: 8, Currency: "EUR", ExchangeRate: 1.1, ExchangeRateCurrency: "USD", ExpenseDate: "2026-01-21",
+					Category: "travel", Payers: []model.ExpensePayer{{UserID: users[2], Amount: 8}}},
+					[]int64{-4, 0, 4}},
+				// Uncategorized and outside January
+				{model.Expense{Amount: 100, ExpenseDate: "2026-02-01",
+					Payers: []model.ExpensePayer{{UserID: users[0], Amount: 100}}},
+					[]int64{100, -100, 0}},
+			}
+			for _, item := range items {
+				expense := item.expense
+				expense.PayerID = expense.Payers[0].UserID
+				expense.Description = "Expense"
+				expense.ExpenseType = "EXACT"
+				balances := item.balances
+				split := func(expense *model.Expense) error {
+					expense.Shares = nil
+					for i, balance := range balances {
+						expense.Shares = append(expense.Shares, model.UserShare{UserID: users[i], ShareAmount: balance})
+					}
+					return nil
+				}
+				if err := repos.Items.Create(groupID, &expense, split); err != nil {
+					t.Fatalf("create item: %v", err)
+				}
+			}
+
+			type key struct {
+				category string
+				user     int
+				currency string
+			}
+			// Rows are compared by position in users, with their paid, owed,
+			// unconverted paid and unconverted owed amounts
+			january := map[key][4]int64{
+				{"food", 0, ""}:      {900, 500, 0, 0},
+				{"food", 1, ""}:      {400, 500, 0, 0},
+				{"food", 2, ""}:      {200, 500, 0, 0},
+				{"travel", 0, "USD"}: {0, 0, 0, 0},
+				{"travel", 0, "EUR"}: {0, 0, 0, 4},
+				{"travel", 1, "USD"}: {800, 400, 0, 0},
+				{"travel", 1, "EUR"}: {0, 0, 0, 0},
+				{"travel", 2, "USD"}: {0, 400, 0, 0},
+				{"travel", 2, "EUR"}: {0, 0, 8, 4},
+			}
+			all := map[key][4]int64{
+				{"", 0, ""}: {100, 0, 0, 0},
+				{"", 1, ""}: {0, 100, 0, 0},
+				{"", 2, ""}: {0, 0, 0, 0},
+			}
+			for k, v := range january {
+				all[k] = v
+			}
+
+			tests := []struct {
+				name     string
+				from, to time.Time
+				want     map[key][4]int64
+			}{
+				{"open range", time.Time{}, time.Time{}, all},
+				{"January", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), january},
+				{"before any expense", time.Time{}, time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), map[key][4]int64{}},
+			}
+			position := make(map[int64]int)
+			for i, userID := range users {
+				position[userID] = i
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					rows, err := repos.Splits.CategorySpend(groupID, "INR", tt.from, tt.to)
+					if err != nil {
+						t.Fatalf("CategorySpend: %v", err)
+					}
+					got := make(map[key][4]int64)
+					for _, row := range rows {
+						k := key{row.Category, position[row.UserID], row.Currency}
+						if _, ok := got[k]; ok {
+							t.Errorf("more than one row for %+v", k)
+						}
+						got[k] = [4]int64{row.Paid, row.Owed, row.UnconvertedPaid, row.UnconvertedOwed}
+					}
+					if !reflect.DeepEqual(got, tt.want) {
+						t.Errorf("got rows %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
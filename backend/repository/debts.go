@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"go-splitwise/model"
+	"math"
+	"sort"
+)
+
+// itemDebt is what one debtor owes one creditor on a single item, in the
+// item's currency
+type itemDebt struct {
+	debtorID   int64
+	creditorID int64
+	amount     int64
+}
+
+// splitItemDebts divides what an item's debtors owe between its creditors.
+// Debtors and creditors are each lined up in user ID order along the item's
+// total and every debtor owes every creditor the stretch their parts have in
+// common, so each debtor's rows add up to their share and each creditor's to
+// theirs without any rounding. sqlSplits.GroupDebts splits items the same way.
+func splitItemDebts(shares []model.UserShare) []itemDebt {
+	var debtors, creditors []model.UserShare
+	for _, share := range shares {
+		switch {
+		case share.ShareAmount < 0:
+			debtors = append(debtors, share)
+		case share.ShareAmount > 0:
+			creditors = append(creditors, share)
+		}
+	}
+	sort.Slice(debtors, func(a, b int) bool { return debtors[a].UserID < debtors[b].UserID })
+	sort.Slice(creditors, func(a, b int) bool { return creditors[a].UserID < creditors[b].UserID })
+
+	var debts []itemDebt
+	c := 0
+	var used int64 = 0
+	for _, debtor := range debtors {
+		owed := -debtor.ShareAmount
+		for owed > 0 && c < len(creditors) {
+			amount := min(owed, creditors[c].ShareAmount-used)
+			debts = append(debts, itemDebt{debtorID: debtor.UserID, creditorID: creditors[c].UserID, amount: amount})
+			owed -= amount
+			used += amount
+			if used == creditors[c].ShareAmount {
+				c++
+				used = 0
+			}
+		}
+	}
+	return debts
+}
+
+// convertDebt converts an item's amount into baseCurrency with the rate
+// pinned on the item, returning it as unconverted when it can't be
+func convertDebt(amount int64, currency string, rate float64, rateCurrency, baseCurrency string) (converted, unconverted int64) {
+	switch {
+	case currency == "" || currency == baseCurrency:
+		return amount, 0
+	case rate > 0 && rateCurrency == baseCurrency:
+		return int64(math.Round(float64(amount) * rate)), 0
+	}
+	return 0, amount
+}
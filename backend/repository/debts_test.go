@@ -0,0 +1,211 @@
+package repository_test
+
+import (
+	"fmt"
+	"go-splitwise/migrations"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"reflect"
+	"testing"
+)
+
+// testRepositories returns an in-memory store and a migrated SQLite one
+func testRepositories(t *testing.T) map[string]repository.Repositories {
+	t.Helper()
+
+	db, dialect, err := repository.Open("sqlite::memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db, string(dialect)); err != nil {
+		t.Fatalf("migrate sqlite: %v", err)
+	}
+
+	return map[string]repository.Repositories{
+		"inmemory": repository.NewInMemory(),
+		"sqlite":   repository.NewSQL(db),
+	}
+}
+
+// createGroup stores n users and a group they all belong to
+func createGroup(t *testing.T, repos repository.Repositories, n int) (int64, []int64) {
+	t.Helper()
+
+	var userIDs []int64
+	for i := 0; i < n; i++ {
+		userID, err := repos.Users.Create(fmt.Sprintf("User %d", i+1), fmt.Sprintf("user%d@example.com", i+1), "hash")
+		if err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	group := model.Group{GroupName: "Flat"}
+	if err := repos.Groups.Create(&group); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	for _, userID := range userIDs {
+		if err := repos.Groups.AddMember(group.GroupID, userID); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+	}
+	return group.GroupID, userIDs
+}
+
+func TestGroupDebtsSplitsEachDebtorExactly(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 5)
+
+			// 10 units paid 4/3/3 by the first three users and owed 5/5 by
+			// the other two
+			balances := []int64{4, 3, 3, -5, -5}
+			expense := model.Expense{
+				Amount:      10,
+				PayerID:     users[0],
+				Description: "Dinner",
+				ExpenseDate: "2026-01-01",
+				ExpenseType: "EXACT",
+				Payers: []model.ExpensePayer{
+					{UserID: users[0], Amount: 4},
+					{UserID: users[1], Amount: 3},
+					{UserID: users[2], Amount: 3},
+				},
+			}
+			split := func(expense *model.Expense) error {
+				expense.Shares = nil
+				for i, balance := range balances {
+					expense.Shares = append(expense.Shares, model.UserShare{UserID: users[i], ShareAmount: balance})
+				}
+				return nil
+			}
+			if err := repos.Items.Create(groupID, &expense, split); err != nil {
+				t.Fatalf("create item: %v", err)
+			}
+
+			debts, err := repos.Splits.GroupDebts(groupID, "INR")
+			if err != nil {
+				t.Fatalf("GroupDebts: %v", err)
+			}
+
+			net := make(map[int64]int64)
+			owed := make(map[int64]int64)
+			for _, debt := range debts {
+				if debt.Unconverted != 0 {
+					t.Errorf("debt %+v has an unconverted amount", debt)
+				}
+				net[debt.CreditorID] += debt.Converted
+				net[debt.DebtorID] -= debt.Converted
+				owed[debt.DebtorID] += debt.Converted
+			}
+			for i, balance := range balances {
+				if net[users[i]] != balance {
+					t.Errorf("user %d nets %d, want %d", i+1, net[users[i]], balance)
+				}
+				if balance < 0 && owed[users[i]] != -balance {
+					t.Errorf("user %d's debts add up to %d, want %d", i+1, owed[users[i]], -balance)
+				}
+			}
+		})
+	}
+}
+
+func TestGroupDebtsAgreeAcrossStores(t *testing.T) {
+	got := make(map[string]map[repository.Debt]bool)
+	for name, repos := range testRepositories(t) {
+		groupID, users := createGroup(t, repos, 4)
+
+		items := []struct {
+			expense  model.Expense
+			balances []int64
+		}{
+			// Two payers and two debtors with different shares
+			{model.Expense{Amount: 1000, Payers: []model.ExpensePayer{{UserID: users[0], Amount: 600}, {UserID: users[1], Amount: 400}}},
+				[]int64{350, 150, -250, -250}},
+			// Pinned to the base currency, so converted
+			{model.Expense{Amount: 7, Currency: "USD", ExchangeRate: 83.5, ExchangeRateCurrency: "INR",
+				Payers: []model.ExpensePayer{{UserID: users[2], Amount: 7}}},
+				[]int64{-3, -2, 7, -2}},
+			// Pinned to another currency, so left unconverted
+			{model.Expense{Amount: 10, Currency: "EUR", ExchangeRate: 1.1, ExchangeRateCurrency: "USD",
+				Payers: []model.ExpensePayer{{UserID: users[3], Amount: 10}}},
+				[]int64{-5, 0, 0, 5}},
+			// Three creditors for one debtor
+			{model.Expense{Amount: 10, Payers: []model.ExpensePayer{{UserID: users[0], Amount: 4}, {UserID: users[1], Amount: 3}, {UserID: users[2], Amount: 3}}},
+				[]int64{4, 3, 3, -10}},
+		}
+		for _, item := range items {
+			expense := item.expense
+			expense.PayerID = expense.Payers[0].UserID
+			expense.Description = "Expense"
+			expense.ExpenseDate = "2026-01-01"
+			expense.ExpenseType = "EXACT"
+			balances := item.balances
+			split := func(expense *model.Expense) error {
+				expense.Shares = nil
+				for i, balance := range balances {
+					expense.Shares = append(expense.Shares, model.UserShare{UserID: users[i], ShareAmount: balance})
+				}
+				return nil
+			}
+			if err := repos.Items.Create(groupID, &expense, split); err != nil {
+				t.Fatalf("%s: create item: %v", name, err)
+			}
+		}
+
+		for _, transaction := range []model.Transactions{
+			{GroupID: groupID, PayerID: users[2], UserID: users[0], Amount: 100},
+			{GroupID: groupID, PayerID: users[2], UserID: users[0], Amount: 50},
+			{GroupID: groupID, PayerID: users[3], UserID: users[1], Amount: 20},
+		} {
+			if err := repos.Transactions.Create(&transaction); err != nil {
+				t.Fatalf("%s: create transaction: %v", name, err)
+			}
+		}
+		reversed := model.Transactions{GroupID: groupID, PayerID: users[3], UserID: users[0], Amount: 500}
+		if err := repos.Transactions.Create(&reversed); err != nil {
+			t.Fatalf("%s: create transaction: %v", name, err)
+		}
+		if _, err := repos.Transactions.Reverse(reversed.ID, users[3]); err != nil {
+			t.Fatalf("%s: reverse transaction: %v", name, err)
+		}
+
+		debts, err := repos.Splits.GroupDebts(groupID, "INR")
+		if err != nil {
+			t.Fatalf("%s: GroupDebts: %v", name, err)
+		}
+
+		// Compare by position in users, as the stores number users
+		// differently, and add up rows for the same pair and currency
+		position := make(map[int64]int64)
+		for i, userID := range users {
+			position[userID] = int64(i)
+		}
+		type key struct {
+			debtor, creditor int64
+			currency         string
+		}
+		totals := make(map[key]repository.Debt)
+		for _, debt := range debts {
+			k := key{position[debt.DebtorID], position[debt.CreditorID], debt.Currency}
+			total := totals[k]
+			total.DebtorID, total.CreditorID, total.Currency = k.debtor, k.creditor, k.currency
+			total.Converted += debt.Converted
+			total.Unconverted += debt.Unconverted
+			totals[k] = total
+		}
+		got[name] = make(map[repository.Debt]bool)
+		for _, total := range totals {
+			got[name][total] = true
+		}
+	}
+
+	if !reflect.DeepEqual(got["inmemory"], got["sqlite"]) {
+		t.Errorf("in-memory debts %v, SQLite debts %v", got["inmemory"], got["sqlite"])
+	}
+	want := repository.Debt{DebtorID: 0, CreditorID: 2, Currency: "USD", Converted: 251}
+	if !got["sqlite"][want] {
+		t.Errorf("debts %v are missing %+v", got["sqlite"], want)
+	}
+}
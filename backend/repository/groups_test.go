@@ -0,0 +1,43 @@
+package repository_test
+
+import (
+	"go-splitwise/model"
+	"testing"
+)
+
+func TestGroupSettingsReturnTheWholeGroup(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			group := model.Group{GroupName: "Trip", BaseCurrency: "USD"}
+			if err := repos.Groups.Create(&group); err != nil {
+				t.Fatalf("create group: %v", err)
+			}
+
+			updated, err := repos.Groups.SetRoundingPolicy(group.GroupID, "ROUND_ROBIN")
+			if err != nil {
+				t.Fatalf("SetRoundingPolicy: %v", err)
+			}
+			want := model.Group{GroupID: group.GroupID, GroupName: "Trip", RoundingPolicy: "ROUND_ROBIN", BaseCurrency: "USD"}
+			if *updated != want {
+				t.Errorf("SetRoundingPolicy returned %+v, want %+v", *updated, want)
+			}
+
+			updated, err = repos.Groups.SetBaseCurrency(group.GroupID, "EUR")
+			if err != nil {
+				t.Fatalf("SetBaseCurrency: %v", err)
+			}
+			want.BaseCurrency = "EUR"
+			if *updated != want {
+				t.Errorf("SetBaseCurrency returned %+v, want %+v", *updated, want)
+			}
+
+			stored, err := repos.Groups.Get(group.GroupID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if *stored != want {
+				t.Errorf("stored group %+v, want %+v", *stored, want)
+			}
+		})
+	}
+}
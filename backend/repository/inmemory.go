@@ -0,0 +1,1088 @@
+package repository
+
+import (
+	"go-splitwise/model"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewInMemory creates repositories that keep everything in memory, for tests
+// and local development without a database
+func NewInMemory() Repositories {
+	store := &inMemoryStore{
+		users:      make(map[int64]*inMemoryUser),
+		groups:     make(map[int64]*model.Group),
+		members:    make(map[int64]map[int64]bool),
+		categories: make(map[int64]map[string]bool),
+		items:      make(map[int64]*inMemoryItem),
+		sessions:   make(map[string]inMemorySession),
+		memories:   make(map[int64]*model.Memory),
+		jobs:       make(map[int64]*inMemoryJob),
+		recurring:  make(map[int64]*model.RecurringExpense),
+		runs:       make(map[inMemoryRun]bool),
+		rates:      make(map[inMemoryRate]float64),
+	}
+	return Repositories{
+		Users:          &inMemoryUsers{store},
+		Groups:         &inMemoryGroups{store},
+		Items:          &inMemoryItems{store},
+		Splits:         &inMemorySplits{store},
+		Transactions:   &inMemoryTransactions{store},
+		Sessions:       &inMemorySessions{store},
+		Memories:       &inMemoryMemories{store},
+		PasswordResets: &inMemoryPasswordResets{store},
+		Reminders:      &inMemoryReminders{store},
+		Recurring:      &inMemoryRecurringExpenses{store},
+		Rates:          &inMemoryRates{store},
+	}
+}
+
+type inMemoryUser struct {
+	user     model.UserResponse
+	googleID string
+}
+
+type inMemoryItem struct {
+	expense   model.Expense
+	createdAt time.Time
+}
+
+type inMemorySession struct {
+	userID    int64
+	expiresAt time.Time
+}
+
+type inMemoryJob struct {
+	jobType  string
+	status   string
+	errorMsg string
+	success  int
+	failed   int
+}
+
+type inMemoryRun struct {
+	recurringID  int64
+	scheduledFor time.Time
+}
+
+// inMemoryStore holds every table behind a single lock
+type inMemoryStore struct {
+	mu           sync.Mutex
+	lastID       int64
+	users        map[int64]*inMemoryUser
+	groups       map[int64]*model.Group
+	members      map[int64]map[int64]bool
+	categories   map[int64]map[string]bool
+	items        map[int64]*inMemoryItem
+	transactions []model.Transactions
+	sessions     map[string]inMemorySession
+	memories     map[int64]*model.Memory
+	resets       []model.PasswordReset
+	jobs         map[int64]*inMemoryJob
+	reminderLogs int
+	recurring    map[int64]*model.RecurringExpense
+	runs         map[inMemoryRun]bool
+	rates        map[inMemoryRate]float64
+}
+
+// nextID returns a fresh ID; IDs are unique across tables, which is fine for
+// callers that only need them to be unique within one
+func (s *inMemoryStore) nextID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *inMemoryStore) sortedUsers(include func(userID int64) bool) []model.UserResponse {
+	var users []model.UserResponse
+	for userID, u := range s.users {
+		if include(userID) {
+			users = append(users, model.UserResponse{UserID: u.user.UserID, Name: u.user.Name, Email: u.user.Email})
+		}
+	}
+	sort.Slice(users, func(a, b int) bool { return users[a].UserID < users[b].UserID })
+	return users
+}
+
+type inMemoryUsers struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryUsers) create(name, email, passwordHash, googleID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.user.Email == email {
+			return 0, ErrDuplicate
+		}
+	}
+	userID := r.nextID()
+	r.users[userID] = &inMemoryUser{
+		user:     model.UserResponse{UserID: userID, Name: name, Email: email, Password: passwordHash},
+		googleID: googleID,
+	}
+	return userID, nil
+}
+
+func (r *inMemoryUsers) Create(name, email, passwordHash string) (int64, error) {
+	return r.create(name, email, passwordHash, "")
+}
+
+func (r *inMemoryUsers) CreateWithGoogle(name, email, googleID string) (int64, error) {
+	return r.create(name, email, "", googleID)
+}
+
+func (r *inMemoryUsers) LinkGoogle(userID int64, googleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if u, ok := r.users[userID]; ok {
+		u.googleID = googleID
+	}
+	return nil
+}
+
+func (r *inMemoryUsers) Get(userID int64) (*model.UserResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &model.UserResponse{UserID: u.user.UserID, Name: u.user.Name, Email: u.user.Email}, nil
+}
+
+func (r *inMemoryUsers) GetByEmail(email string) (*model.UserResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.user.Email == email {
+			user := u.user
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *inMemoryUsers) GetByGoogleID(googleID string) (*model.UserResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if googleID != "" && u.googleID == googleID {
+			return &model.UserResponse{UserID: u.user.UserID, Name: u.user.Name, Email: u.user.Email}, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *inMemoryUsers) UpdatePassword(userID int64, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.user.Password = passwordHash
+	return nil
+}
+
+func (r *inMemoryUsers) UpdatePasswordByEmail(email, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if u.user.Email == email {
+			u.user.Password = passwordHash
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryUsers) List() ([]model.UserResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sortedUsers(func(int64) bool { return true }), nil
+}
+
+func (r *inMemoryUsers) ListNotInGroup(groupID int64) ([]model.UserResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sortedUsers(func(userID int64) bool { return !r.members[groupID][userID] }), nil
+}
+
+type inMemoryGroups struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryGroups) Create(group *model.Group) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	group.GroupID = r.nextID()
+	stored := *group
+	r.groups[group.GroupID] = &stored
+	return nil
+}
+
+func (r *inMemoryGroups) Get(groupID int64) (*model.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, ok := r.groups[groupID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	group := *g
+	return &group, nil
+}
+
+func (r *inMemoryGroups) ListByUser(userID int64) ([]model.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var groups []model.Group
+	for groupID, g := range r.groups {
+		if r.members[groupID][userID] {
+			groups = append(groups, *g)
+		}
+	}
+	sort.Slice(groups, func(a, b int) bool { return groups[a].GroupID < groups[b].GroupID })
+	return groups, nil
+}
+
+func (r *inMemoryGroups) SetRoundingPolicy(groupID int64, policy string) (*model.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, ok := r.groups[groupID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	g.RoundingPolicy = policy
+	group := *g
+	return &group, nil
+}
+
+func (r *inMemoryGroups) SetBaseCurrency(groupID int64, currency string) (*model.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, ok := r.groups[groupID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	g.BaseCurrency = currency
+	group := *g
+	return &group, nil
+}
+
+func (r *inMemoryGroups) AddMember(groupID, userID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.members[groupID] == nil {
+		r.members[groupID] = make(map[int64]bool)
+	}
+	r.members[groupID][userID] = true
+	return nil
+}
+
+func (r *inMemoryGroups) IsMember(groupID, userID int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.members[groupID][userID], nil
+}
+
+func (r *inMemoryGroups) Members(groupID int64) ([]model.UserResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sortedUsers(func(userID int64) bool { return r.members[groupID][userID] }), nil
+}
+
+func (r *inMemoryGroups) MemberIDs(groupID int64) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var userIDs []int64
+	for userID := range r.members[groupID] {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(a, b int) bool { return userIDs[a] < userIDs[b] })
+	return userIDs, nil
+}
+
+func (r *inMemoryGroups) Categories(groupID int64) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var names []string
+	for name := range r.categories[groupID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (r *inMemoryGroups) AddCategory(groupID int64, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.categories[groupID][name] {
+		return ErrDuplicate
+	}
+	if r.categories[groupID] == nil {
+		r.categories[groupID] = make(map[string]bool)
+	}
+	r.categories[groupID][name] = true
+	return nil
+}
+
+type inMemoryItems struct {
+	*inMemoryStore
+}
+
+// stored returns a copy of an expense as kept in the store, with its shares
+// and payers copied so callers can't modify them
+func (item *inMemoryItem) stored() model.Expense {
+	expense := item.expense
+	expense.Shares = append([]model.UserShare(nil), item.expense.Shares...)
+	expense.Payers = append([]model.ExpensePayer(nil), item.expense.Payers...)
+	return expense
+}
+
+func (r *inMemoryItems) Create(groupID int64, expense *model.Expense, split SplitFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insertItem(groupID, expense, split)
+}
+
+func (s *inMemoryStore) insertItem(groupID int64, expense *model.Expense, split SplitFunc) error {
+	createdAt := time.Now()
+	expense.ExpenseID = s.nextID()
+	expense.GroupID = groupID
+	expense.Created_at = createdAt.Format(time.RFC3339Nano)
+	if err := split(expense); err != nil {
+		return err
+	}
+
+	item := &inMemoryItem{expense: *expense, createdAt: createdAt}
+	item.expense = item.stored()
+	s.items[expense.ExpenseID] = item
+	return nil
+}
+
+func (r *inMemoryItems) Update(expense *model.Expense, split SplitFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.items[expense.ExpenseID]
+	if !ok {
+		return ErrNotFound
+	}
+	expense.GroupID = existing.expense.GroupID
+	expense.Created_at = existing.expense.Created_at
+	if err := split(expense); err != nil {
+		return err
+	}
+
+	item := &inMemoryItem{expense: *expense, createdAt: existing.createdAt}
+	item.expense = item.stored()
+	r.items[expense.ExpenseID] = item
+	return nil
+}
+
+func (r *inMemoryItems) Delete(itemID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.items[itemID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.items, itemID)
+	return nil
+}
+
+func (r *inMemoryItems) Get(itemID int64) (*model.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[itemID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	expense := item.expense
+	expense.Shares = nil
+	expense.Payers = nil
+	return &expense, nil
+}
+
+// matches reports whether an expense passes every filter but the cursor and
+// limit
+func (filter ItemFilter) matches(expense model.Expense) bool {
+	if filter.PayerID != 0 && expense.PayerID != filter.PayerID {
+		paid := false
+		for _, payer := range expense.Payers {
+			paid = paid || payer.UserID == filter.PayerID
+		}
+		if !paid {
+			return false
+		}
+	}
+	if filter.ParticipantID != 0 {
+		participant := false
+		for _, share := range expense.Shares {
+			participant = participant || share.UserID == filter.ParticipantID
+		}
+		if !participant {
+			return false
+		}
+	}
+	if (filter.From != "" && expense.ExpenseDate < filter.From) ||
+		(filter.To != "" && expense.ExpenseDate > filter.To) ||
+		(filter.MinAmount != 0 && expense.Amount < filter.MinAmount) ||
+		(filter.MaxAmount != 0 && expense.Amount > filter.MaxAmount) ||
+		(filter.Category != nil && expense.Category != *filter.Category) {
+		return false
+	}
+	description := strings.ToLower(expense.Description)
+	for _, word := range strings.Fields(strings.ToLower(filter.Search)) {
+		if !strings.Contains(description, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *inMemoryItems) ListByGroup(groupID int64, filter ItemFilter) ([]model.Expense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var items []*inMemoryItem
+	for _, item := range r.items {
+		expense := item.expense
+		if expense.GroupID != groupID || !filter.matches(expense) {
+			continue
+		}
+		if after := filter.After; after != nil && (expense.ExpenseDate > after.ExpenseDate ||
+			(expense.ExpenseDate == after.ExpenseDate && expense.ExpenseID >= after.ItemID)) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(a, b int) bool {
+		if items[a].expense.ExpenseDate != items[b].expense.ExpenseDate {
+			return items[a].expense.ExpenseDate > items[b].expense.ExpenseDate
+		}
+		return items[a].expense.ExpenseID > items[b].expense.ExpenseID
+	})
+	if filter.Limit > 0 && len(items) > filter.Limit {
+		items = items[:filter.Limit]
+	}
+
+	expenses := make([]model.Expense, len(items))
+	for i, item := range items {
+		expenses[i] = item.expense
+		expenses[i].Shares = nil
+		expenses[i].Payers = nil
+	}
+	return expenses, nil
+}
+
+type inMemorySplits struct {
+	*inMemoryStore
+}
+
+func (r *inMemorySplits) ListByItem(itemID int64) ([]model.UserShare, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[itemID]
+	if !ok {
+		return nil, nil
+	}
+	return item.stored().Shares, nil
+}
+
+func (r *inMemorySplits) Payers(itemID int64) ([]model.ExpensePayer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[itemID]
+	if !ok {
+		return nil, nil
+	}
+	return item.stored().Payers, nil
+}
+
+// GroupDebts mirrors the SQL implementation
+func (r *inMemorySplits) GroupDebts(groupID int64, baseCurrency string) ([]Debt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type key struct {
+		debtorID, creditorID int64
+		currency             string
+	}
+	totals := make(map[key]*Debt)
+	add := func(k key, converted, unconverted int64) {
+		debt, ok := totals[k]
+		if !ok {
+			debt = &Debt{DebtorID: k.debtorID, CreditorID: k.creditorID, Currency: k.currency}
+			totals[k] = debt
+		}
+		debt.Converted += converted
+		debt.Unconverted += unconverted
+	}
+
+	for _, item := range r.items {
+		expense := item.expense
+		if expense.GroupID != groupID {
+			continue
+		}
+
+		for _, debt := range splitItemDebts(expense.Shares) {
+			converted, unconverted := convertDebt(debt.amount, expense.Currency, expense.ExchangeRate, expense.ExchangeRateCurrency, baseCurrency)
+			add(key{debt.debtorID, debt.creditorID, expense.Currency}, converted, unconverted)
+		}
+	}
+
+	var debts []Debt
+	for _, debt := range totals {
+		debts = append(debts, *debt)
+	}
+
+	paid := make(map[key]int64)
+	for _, t := range r.transactions {
+		if t.GroupID == groupID && t.ReversedAt == nil {
+			paid[key{t.UserID, t.PayerID, ""}] += t.Amount
+		}
+	}
+	for k, amount := range paid {
+		debts = append(debts, Debt{DebtorID: k.debtorID, CreditorID: k.creditorID, Converted: amount})
+	}
+
+	return debts, nil
+}
+
+// CategorySpend mirrors the aggregate query of the SQL implementation
+func (r *inMemorySplits) CategorySpend(groupID int64, baseCurrency string, from, to time.Time) ([]CategorySpend, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type key struct {
+		category string
+		userID   int64
+		currency string
+	}
+	totals := make(map[key]*CategorySpend)
+	add := func(k key, paid, owed int64, converted bool) {
+		row, ok := totals[k]
+		if !ok {
+			row = &CategorySpend{Category: k.category, UserID: k.userID, Currency: k.currency}
+			totals[k] = row
+		}
+		if converted {
+			row.Paid += paid
+			row.Owed += owed
+		} else {
+			row.UnconvertedPaid += paid
+			row.UnconvertedOwed += owed
+		}
+	}
+
+	for _, item := range r.items {
+		expense := item.expense
+		if expense.GroupID != groupID ||
+			(!from.IsZero() && expense.ExpenseDate < from.Format(time.DateOnly)) ||
+			(!to.IsZero() && expense.ExpenseDate >= to.Format(time.DateOnly)) {
+			continue
+		}
+
+		paid := make(map[int64]int64)
+		for _, payer := range expense.Payers {
+			paid[payer.UserID] += payer.Amount
+		}
+		for _, share := range expense.Shares {
+			k := key{expense.Category, share.UserID, expense.Currency}
+			userPaid, owed := paid[share.UserID], paid[share.UserID]-share.ShareAmount
+			switch {
+			case expense.Currency == "" || expense.Currency == baseCurrency:
+				add(k, userPaid, owed, true)
+			case expense.ExchangeRate > 0 && expense.ExchangeRateCurrency == baseCurrency:
+				add(k, int64(math.Round(float64(userPaid)*expense.ExchangeRate)),
+					int64(math.Round(float64(owed)*expense.ExchangeRate)), true)
+			default:
+				add(k, userPaid, owed, false)
+			}
+		}
+	}
+
+	var spend []CategorySpend
+	for _, row := range totals {
+		spend = append(spend, *row)
+	}
+	return spend, nil
+}
+
+type inMemoryTransactions struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryTransactions) Create(transaction *model.Transactions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.insert(transaction)
+	return nil
+}
+
+func (r *inMemoryTransactions) CreateBatch(transactions []model.Transactions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range transactions {
+		if i > 0 {
+			transactions[i].BatchID = transactions[0].ID
+		}
+		r.insert(&transactions[i])
+	}
+	if len(transactions) > 0 {
+		transactions[0].BatchID = transactions[0].ID
+		r.find(transactions[0].ID).BatchID = transactions[0].ID
+	}
+	return nil
+}
+
+func (r *inMemoryTransactions) insert(transaction *model.Transactions) {
+	if transaction.Kind == "" {
+		transaction.Kind = model.TransactionPayment
+	}
+	transaction.ID = r.nextID()
+	transaction.CreatedAt = time.Now()
+	r.transactions = append(r.transactions, *transaction)
+}
+
+func (r *inMemoryTransactions) find(transactionID int64) *model.Transactions {
+	for i := range r.transactions {
+		if r.transactions[i].ID == transactionID {
+			return &r.transactions[i]
+		}
+	}
+	return nil
+}
+
+func (r *inMemoryTransactions) Get(transactionID int64) (*model.Transactions, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction := r.find(transactionID)
+	if transaction == nil {
+		return nil, ErrNotFound
+	}
+	t := *transaction
+	return &t, nil
+}
+
+func (r *inMemoryTransactions) Reverse(transactionID, userID int64) (*model.Transactions, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction := r.find(transactionID)
+	if transaction == nil {
+		return nil, ErrNotFound
+	}
+	if transaction.ReversedAt != nil {
+		return nil, ErrAlreadyReversed
+	}
+
+	reversedAt := time.Now()
+	transaction.ReversedAt = &reversedAt
+	transaction.ReversedBy = userID
+	t := *transaction
+	return &t, nil
+}
+
+func (r *inMemoryTransactions) ReverseBatch(batchID, userID int64) ([]model.Transactions, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := false
+	reversedAt := time.Now()
+	var transactions []model.Transactions
+	for i := range r.transactions {
+		transaction := &r.transactions[i]
+		if transaction.BatchID != batchID {
+			continue
+		}
+		found = true
+		if transaction.ReversedAt != nil {
+			continue
+		}
+		transaction.ReversedAt = &reversedAt
+		transaction.ReversedBy = userID
+		transactions = append(transactions, *transaction)
+	}
+
+	if !found {
+		return nil, ErrNotFound
+	}
+	if len(transactions) == 0 {
+		return nil, ErrAlreadyReversed
+	}
+	return transactions, nil
+}
+
+func (r *inMemoryTransactions) ListByGroup(groupID int64, filter TransactionFilter) ([]model.Transactions, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var transactions []model.Transactions
+	for i := len(r.transactions) - 1; i >= 0; i-- {
+		t := r.transactions[i]
+		if t.GroupID != groupID ||
+			(filter.PayerID != 0 && t.PayerID != filter.PayerID) ||
+			(filter.ReceiverID != 0 && t.UserID != filter.ReceiverID) ||
+			(!filter.From.IsZero() && t.CreatedAt.Before(filter.From)) ||
+			(!filter.To.IsZero() && !t.CreatedAt.Before(filter.To)) ||
+			(filter.BeforeID != 0 && t.ID >= filter.BeforeID) {
+			continue
+		}
+		transactions = append(transactions, t)
+		if filter.Limit > 0 && len(transactions) == filter.Limit {
+			break
+		}
+	}
+	return transactions, nil
+}
+
+type inMemoryRecurringExpenses struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryRecurringExpenses) Create(recurring *model.RecurringExpense) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recurring.ID = r.nextID()
+	recurring.CreatedAt = time.Now()
+	stored := *recurring
+	r.recurring[recurring.ID] = &stored
+	return nil
+}
+
+func (r *inMemoryRecurringExpenses) Get(recurringID int64) (*model.RecurringExpense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recurring, ok := r.recurring[recurringID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	rec := *recurring
+	return &rec, nil
+}
+
+func (r *inMemoryRecurringExpenses) list(include func(rec *model.RecurringExpense) bool) []model.RecurringExpense {
+	var recurring []model.RecurringExpense
+	for _, rec := range r.recurring {
+		if include(rec) {
+			recurring = append(recurring, *rec)
+		}
+	}
+	sort.Slice(recurring, func(a, b int) bool { return recurring[a].ID < recurring[b].ID })
+	return recurring
+}
+
+func (r *inMemoryRecurringExpenses) ListByGroup(groupID int64) ([]model.RecurringExpense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.list(func(rec *model.RecurringExpense) bool { return rec.GroupID == groupID }), nil
+}
+
+func (r *inMemoryRecurringExpenses) Deactivate(recurringID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recurring, ok := r.recurring[recurringID]
+	if !ok {
+		return ErrNotFound
+	}
+	recurring.Active = false
+	return nil
+}
+
+func (r *inMemoryRecurringExpenses) Fail(recurringID int64, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recurring, ok := r.recurring[recurringID]
+	if !ok {
+		return ErrNotFound
+	}
+	recurring.Active = false
+	recurring.LastError = reason
+	return nil
+}
+
+func (r *inMemoryRecurringExpenses) Due(now time.Time) ([]model.RecurringExpense, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.list(func(rec *model.RecurringExpense) bool { return rec.Active && !rec.NextRunAt.After(now) }), nil
+}
+
+func (r *inMemoryRecurringExpenses) Materialize(recurringID int64, scheduledFor, nextRunAt time.Time, expense *model.Expense, split SplitFunc) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recurring, ok := r.recurring[recurringID]
+	if !ok {
+		return false, ErrNotFound
+	}
+
+	run := inMemoryRun{recurringID: recurringID, scheduledFor: scheduledFor.UTC()}
+	claimed := !r.runs[run]
+	if claimed {
+		if err := r.insertItem(expense.GroupID, expense, split); err != nil {
+			return false, err
+		}
+		r.runs[run] = true
+	}
+
+	if !recurring.NextRunAt.After(scheduledFor) {
+		recurring.NextRunAt = nextRunAt
+		lastRunAt := scheduledFor
+		recurring.LastRunAt = &lastRunAt
+	}
+	return claimed, nil
+}
+
+type inMemorySessions struct {
+	*inMemoryStore
+}
+
+func (r *inMemorySessions) Create(sessionID string, userID int64, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[sessionID] = inMemorySession{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *inMemorySessions) Get(sessionID string) (int64, time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[sessionID]
+	if !ok {
+		return 0, time.Time{}, ErrNotFound
+	}
+	return session.userID, session.expiresAt, nil
+}
+
+func (r *inMemorySessions) Delete(sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, sessionID)
+	return nil
+}
+
+func (r *inMemorySessions) DeleteExpired() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for sessionID, session := range r.sessions {
+		if session.expiresAt.Before(now) {
+			delete(r.sessions, sessionID)
+		}
+	}
+	return nil
+}
+
+type inMemoryMemories struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryMemories) Create(groupID int64, filename, imageURL string) (*model.Memory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	memory := &model.Memory{
+		ID:        int(r.nextID()),
+		GroupID:   int(groupID),
+		Filename:  filename,
+		ImageURL:  imageURL,
+		CreatedAt: time.Now(),
+	}
+	stored := *memory
+	r.memories[int64(memory.ID)] = &stored
+	return memory, nil
+}
+
+func (r *inMemoryMemories) Get(memoryID int64) (*model.Memory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.memories[memoryID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	memory := *m
+	return &memory, nil
+}
+
+func (r *inMemoryMemories) ListByGroup(groupID int64) ([]model.Memory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var memories []model.Memory
+	for _, m := range r.memories {
+		if int64(m.GroupID) == groupID {
+			memories = append(memories, *m)
+		}
+	}
+	sort.Slice(memories, func(a, b int) bool {
+		if !memories[a].CreatedAt.Equal(memories[b].CreatedAt) {
+			return memories[a].CreatedAt.After(memories[b].CreatedAt)
+		}
+		return memories[a].ID > memories[b].ID
+	})
+	return memories, nil
+}
+
+func (r *inMemoryMemories) Delete(memoryID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.memories[memoryID]; !ok {
+		return ErrNotFound
+	}
+	delete(r.memories, memoryID)
+	return nil
+}
+
+type inMemoryPasswordResets struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryPasswordResets) Create(reset model.PasswordReset) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset.ID = r.nextID()
+	reset.CreatedAt = time.Now()
+	r.resets = append(r.resets, reset)
+	return nil
+}
+
+func (r *inMemoryPasswordResets) Latest(email string) (*model.PasswordReset, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := len(r.resets) - 1; i >= 0; i-- {
+		if r.resets[i].Email == email {
+			reset := r.resets[i]
+			return &reset, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *inMemoryPasswordResets) MarkUsed(resetID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.resets {
+		if r.resets[i].ID == resetID {
+			r.resets[i].Used = true
+		}
+	}
+	return nil
+}
+
+type inMemoryReminders struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryReminders) StartJob(jobType, status string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobID := r.nextID()
+	r.jobs[jobID] = &inMemoryJob{jobType: jobType, status: status}
+	return jobID, nil
+}
+
+func (r *inMemoryReminders) FinishJob(jobID int64, status, errorMsg string, success, failed int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job, ok := r.jobs[jobID]; ok {
+		job.status = status
+		job.errorMsg = errorMsg
+		job.success = success
+		job.failed = failed
+	}
+	return nil
+}
+
+func (r *inMemoryReminders) LogSent(userID int64, reminderType string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reminderLogs++
+	return nil
+}
+
+type inMemoryRate struct {
+	date, base, quote string
+}
+
+type inMemoryRates struct {
+	*inMemoryStore
+}
+
+func (r *inMemoryRates) Save(rates []ExchangeRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rate := range rates {
+		r.rates[inMemoryRate{rate.Date, rate.Base, rate.Quote}] = rate.Rate
+	}
+	return nil
+}
+
+func (r *inMemoryRates) Find(date, base, quote string) (*ExchangeRate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found *ExchangeRate
+	for k, value := range r.rates {
+		direct := k.base == base && k.quote == quote
+		if !direct && !(k.base == quote && k.quote == base) || k.date > date {
+			continue
+		}
+		if found == nil || k.date > found.Date || (k.date == found.Date && direct) {
+			found = &ExchangeRate{Date: k.date, Base: k.base, Quote: k.quote, Rate: value}
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
@@ -0,0 +1,157 @@
+package repository_test
+
+import (
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"reflect"
+	"testing"
+)
+
+// createItems stores the group's expenses for the listing tests and returns
+// their IDs in the order they were created
+func createItems(t *testing.T, repos repository.Repositories, groupID int64, users []int64) []int64 {
+	t.Helper()
+
+	items := []struct {
+		description string
+		date        string
+		amount      int64
+		category    string
+		payers      []model.ExpensePayer
+		sharedBy    []int64
+	}{
+		{"Weekly groceries", "2026-01-05", 1200, "food", []model.ExpensePayer{{UserID: users[0], Amount: 1200}}, []int64{users[0], users[1]}},
+		{"Train tickets", "2026-01-05", 3000, "travel", []model.ExpensePayer{{UserID: users[1], Amount: 3000}}, users},
+		{"Rent January", "2026-01-01", 90000, "rent", []model.ExpensePayer{{UserID: users[0], Amount: 90000}}, users},
+		{"Groceries 50%_off", "2026-01-10", 500, "", []model.ExpensePayer{{UserID: users[2], Amount: 300}, {UserID: users[1], Amount: 200}}, []int64{users[2], users[1]}},
+		{"Dinner", "2026-01-03", 800, "food", []model.ExpensePayer{{UserID: users[2], Amount: 800}}, []int64{users[2], users[0]}},
+	}
+
+	var ids []int64
+	for _, item := range items {
+		expense := model.Expense{
+			Amount:      item.amount,
+			PayerID:     item.payers[0].UserID,
+			Payers:      item.payers,
+			Description: item.description,
+			Category:    item.category,
+			ExpenseDate: item.date,
+			ExpenseType: "EQUAL",
+		}
+		sharedBy := item.sharedBy
+		split := func(expense *model.Expense) error {
+			expense.Shares = nil
+			for _, userID := range sharedBy {
+				expense.Shares = append(expense.Shares, model.UserShare{UserID: userID})
+			}
+			return nil
+		}
+		if err := repos.Items.Create(groupID, &expense, split); err != nil {
+			t.Fatalf("create item: %v", err)
+		}
+		ids = append(ids, expense.ExpenseID)
+	}
+	return ids
+}
+
+func itemIDs(items []model.Expense) []int64 {
+	ids := []int64{}
+	for _, item := range items {
+		ids = append(ids, item.ExpenseID)
+	}
+	return ids
+}
+
+func TestListItemsFilters(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 3)
+
+			// The same expenses in another group must never show up
+			other := model.Group{GroupName: "Trip"}
+			if err := repos.Groups.Create(&other); err != nil {
+				t.Fatalf("create group: %v", err)
+			}
+			createItems(t, repos, other.GroupID, users)
+			ids := createItems(t, repos, groupID, users)
+			groceries, train, rent, discounted, dinner := ids[0], ids[1], ids[2], ids[3], ids[4]
+
+			food, uncategorized := "food", ""
+			tests := []struct {
+				name   string
+				filter repository.ItemFilter
+				want   []int64
+			}{
+				{"latest expense date first, newest first within a day", repository.ItemFilter{}, []int64{discounted, train, groceries, dinner, rent}},
+				{"paid by, including as one of several payers", repository.ItemFilter{PayerID: users[1]}, []int64{discounted, train}},
+				{"shared by", repository.ItemFilter{ParticipantID: users[2]}, []int64{discounted, train, dinner, rent}},
+				{"date range is inclusive", repository.ItemFilter{From: "2026-01-03", To: "2026-01-05"}, []int64{train, groceries, dinner}},
+				{"amount range is inclusive", repository.ItemFilter{MinAmount: 1200, MaxAmount: 3000}, []int64{train, groceries}},
+				{"category", repository.ItemFilter{Category: &food}, []int64{groceries, dinner}},
+				{"uncategorized", repository.ItemFilter{Category: &uncategorized}, []int64{discounted}},
+				{"search ignores case", repository.ItemFilter{Search: "GROCERIES"}, []int64{discounted, groceries}},
+				{"search matches every word", repository.ItemFilter{Search: "groceries weekly"}, []int64{groceries}},
+				{"search treats wildcards literally", repository.ItemFilter{Search: "%_"}, []int64{discounted}},
+				{"search without matches", repository.ItemFilter{Search: "flights"}, []int64{}},
+				{"filters combine", repository.ItemFilter{PayerID: users[0], Category: &food}, []int64{groceries}},
+				{"limit", repository.ItemFilter{Limit: 2}, []int64{discounted, train}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					items, err := repos.Items.ListByGroup(groupID, tt.filter)
+					if err != nil {
+						t.Fatalf("ListByGroup: %v", err)
+					}
+					if got := itemIDs(items); !reflect.DeepEqual(got, tt.want) {
+						t.Errorf("got items %v, want %v", got, tt.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestListItemsPages(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 3)
+			createItems(t, repos, groupID, users)
+
+			all, err := repos.Items.ListByGroup(groupID, repository.ItemFilter{})
+			if err != nil {
+				t.Fatalf("ListByGroup: %v", err)
+			}
+
+			for _, filter := range []repository.ItemFilter{{}, {ParticipantID: users[2]}} {
+				want, err := repos.Items.ListByGroup(groupID, filter)
+				if err != nil {
+					t.Fatalf("ListByGroup: %v", err)
+				}
+
+				// Pages of two, continuing from the last item of each page,
+				// cover every item once and in order, across equal dates too
+				var paged []model.Expense
+				filter.Limit = 2
+				for page := 0; ; page++ {
+					if page > len(all) {
+						t.Fatalf("paging with %+v doesn't end", filter)
+					}
+					items, err := repos.Items.ListByGroup(groupID, filter)
+					if err != nil {
+						t.Fatalf("ListByGroup: %v", err)
+					}
+					paged = append(paged, items...)
+					if len(items) < filter.Limit {
+						break
+					}
+					last := items[len(items)-1]
+					filter.After = &repository.ItemCursor{ExpenseDate: last.ExpenseDate, ItemID: last.ExpenseID}
+				}
+
+				if got, wantIDs := itemIDs(paged), itemIDs(want); !reflect.DeepEqual(got, wantIDs) {
+					t.Errorf("pages hold items %v, want %v", got, wantIDs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"errors"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"testing"
+	"time"
+)
+
+func TestFailRecurringExpense(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 2)
+
+			now := time.Now().UTC().Truncate(time.Second)
+			recurring := model.RecurringExpense{
+				GroupID:     groupID,
+				Amount:      1000,
+				PayerID:     users[0],
+				Payers:      []model.ExpensePayer{{UserID: users[0], Amount: 1000}},
+				ExpenseType: "EQUAL",
+				Shares:      []model.UserShare{{UserID: users[0]}, {UserID: users[1]}},
+				Currency:    "EUR",
+				Cadence:     "daily",
+				StartAt:     now.Add(-time.Hour),
+				NextRunAt:   now.Add(-time.Hour),
+				Active:      true,
+				CreatedBy:   users[0],
+			}
+			if err := repos.Recurring.Create(&recurring); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			const reason = "no EUR/INR rate"
+			if err := repos.Recurring.Fail(recurring.ID, reason); err != nil {
+				t.Fatalf("Fail: %v", err)
+			}
+			stored, err := repos.Recurring.Get(recurring.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if stored.Active || stored.LastError != reason {
+				t.Errorf("after Fail active = %v, last error = %q", stored.Active, stored.LastError)
+			}
+
+			due, err := repos.Recurring.Due(now)
+			if err != nil {
+				t.Fatalf("Due: %v", err)
+			}
+			if len(due) != 0 {
+				t.Errorf("%d recurring expenses due after failing", len(due))
+			}
+
+			if err := repos.Recurring.Fail(recurring.ID+1000, reason); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("failing a missing recurring expense: %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
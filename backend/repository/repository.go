@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"errors"
+	"go-splitwise/model"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is returned when a row violates a uniqueness constraint
+	ErrDuplicate = errors.New("duplicate")
+	// ErrAlreadyReversed is returned when reversing a transaction twice
+	ErrAlreadyReversed = errors.New("already reversed")
+)
+
+// Debt is what one user owes another within a group, aggregated per currency.
+// Converted is already in the group's base currency; Unconverted is in
+// Currency and still has to be converted at the latest rate.
+type Debt struct {
+	DebtorID    int64
+	CreditorID  int64
+	Currency    string
+	Converted   int64
+	Unconverted int64
+}
+
+// CategorySpend is what one user paid and what their share came to in one
+// category of a group, aggregated per currency like Debt
+type CategorySpend struct {
+	Category        string
+	UserID          int64
+	Currency        string
+	Paid            int64
+	Owed            int64
+	UnconvertedPaid int64
+	UnconvertedOwed int64
+}
+
+// ExchangeRate is the value of one unit of Base expressed in Quote, published
+// on Date as YYYY-MM-DD
+type ExchangeRate struct {
+	Date  string
+	Base  string
+	Quote string
+	Rate  float64
+}
+
+// ItemCursor is the position of the last expense on a page, in the order
+// ItemRepository.ListByGroup returns them
+type ItemCursor struct {
+	ExpenseDate string
+	ItemID      int64
+}
+
+// ItemFilter selects and pages a group's expenses. Zero fields are ignored.
+type ItemFilter struct {
+	// PayerID matches expenses the user paid towards
+	PayerID int64
+	// ParticipantID matches expenses the user has a share in
+	ParticipantID int64
+	// From and To bound the expense date, both inclusive, as YYYY-MM-DD
+	From string
+	To   string
+	// MinAmount and MaxAmount bound the amount, in the expense's currency
+	MinAmount int64
+	MaxAmount int64
+	// Category matches expenses in a category; an empty one matches
+	// uncategorized expenses
+	Category *string
+	// Search matches expenses whose description contains every word of it,
+	// ignoring case
+	Search string
+	// After continues from the end of a previous page
+	After *ItemCursor
+	Limit int
+}
+
+// TransactionFilter selects and pages a group's transactions. Zero fields are
+// ignored.
+type TransactionFilter struct {
+	PayerID    int64
+	ReceiverID int64
+	// From and To bound when the transaction was recorded; To is exclusive
+	From time.Time
+	To   time.Time
+	// BeforeID continues from the end of a previous page
+	BeforeID int64
+	Limit    int
+}
+
+// SplitFunc computes an expense's shares once its ID is known. It runs inside
+// the write so a failing split leaves nothing behind.
+type SplitFunc func(expense *model.Expense) error
+
+type UserRepository interface {
+	// Create stores a user with a password login and returns its ID
+	Create(name, email, passwordHash string) (int64, error)
+	// CreateWithGoogle stores a user who signed up with Google and returns its ID
+	CreateWithGoogle(name, email, googleID string) (int64, error)
+	// LinkGoogle attaches a Google account to an existing user
+	LinkGoogle(userID int64, googleID string) error
+	Get(userID int64) (*model.UserResponse, error)
+	// GetByEmail returns the user with their password hash in Password
+	GetByEmail(email string) (*model.UserResponse, error)
+	GetByGoogleID(googleID string) (*model.UserResponse, error)
+	UpdatePassword(userID int64, passwordHash string) error
+	UpdatePasswordByEmail(email, passwordHash string) error
+	List() ([]model.UserResponse, error)
+	// ListNotInGroup returns the users who could still be added to a group
+	ListNotInGroup(groupID int64) ([]model.UserResponse, error)
+}
+
+type GroupRepository interface {
+	// Create stores a group and sets its GroupID
+	Create(group *model.Group) error
+	// Get returns a group; RoundingPolicy and BaseCurrency are empty when the
+	// group never chose one
+	Get(groupID int64) (*model.Group, error)
+	ListByUser(userID int64) ([]model.Group, error)
+	SetRoundingPolicy(groupID int64, policy string) (*model.Group, error)
+	SetBaseCurrency(groupID int64, currency string) (*model.Group, error)
+	// AddMember adds a user to a group, doing nothing if they already belong
+	AddMember(groupID, userID int64) error
+	IsMember(groupID, userID int64) (bool, error)
+	Members(groupID int64) ([]model.UserResponse, error)
+	MemberIDs(groupID int64) ([]int64, error)
+	// Categories returns the names of the categories a group added, sorted
+	Categories(groupID int64) ([]string, error)
+	// AddCategory adds a custom category to a group, returning ErrDuplicate
+	// when the group already has it
+	AddCategory(groupID int64, name string) error
+}
+
+type ItemRepository interface {
+	// Create stores an expense in a group along with the shares, payers and
+	// rounding computed by split
+	Create(groupID int64, expense *model.Expense, split SplitFunc) error
+	// Update replaces an expense's details and recomputes its shares with split
+	Update(expense *model.Expense, split SplitFunc) error
+	// Delete removes an expense along with its shares and payers
+	Delete(itemID int64) error
+	// Get returns an expense's details without its shares or payers
+	Get(itemID int64) (*model.Expense, error)
+	// ListByGroup returns the group's expenses matching filter, latest
+	// expense date first and newest first within a day, without their shares
+	// or payers
+	ListByGroup(groupID int64, filter ItemFilter) ([]model.Expense, error)
+}
+
+type RecurringExpenseRepository interface {
+	// Create stores a recurring expense and sets its ID and CreatedAt
+	Create(recurring *model.RecurringExpense) error
+	Get(recurringID int64) (*model.RecurringExpense, error)
+	// ListByGroup returns a group's recurring expenses, including stopped ones
+	ListByGroup(groupID int64) ([]model.RecurringExpense, error)
+	// Deactivate stops a recurring expense from being posted again
+	Deactivate(recurringID int64) error
+	// Fail stops a recurring expense whose next occurrence can't be posted and
+	// records why
+	Fail(recurringID int64, reason string) error
+	// Due returns the active recurring expenses whose next run is at or
+	// before now
+	Due(now time.Time) ([]model.RecurringExpense, error)
+	// Materialize posts the occurrence scheduled for scheduledFor as an item
+	// in expense.GroupID, with its shares computed by split, and moves the
+	// next run to nextRunAt.
+	// It returns false without posting anything when that occurrence was
+	// already posted.
+	Materialize(recurringID int64, scheduledFor, nextRunAt time.Time, expense *model.Expense, split SplitFunc) (bool, error)
+}
+
+type SplitRepository interface {
+	// ListByItem returns each user's balance for an item
+	ListByItem(itemID int64) ([]model.UserShare, error)
+	// Payers returns who paid for an item; items recorded before multiple
+	// payers were supported have none stored
+	Payers(itemID int64) ([]model.ExpensePayer, error)
+	// GroupDebts returns every pairwise debt in a group from its items and
+	// unreversed transactions, converting what it can into baseCurrency
+	GroupDebts(groupID int64, baseCurrency string) ([]Debt, error)
+	// CategorySpend returns what each user paid and owes per category of a
+	// group's expenses dated in [from, to), converting what it can into
+	// baseCurrency. Only the dates of from and to are used, and a zero from or
+	// to leaves that end of the range open.
+	CategorySpend(groupID int64, baseCurrency string, from, to time.Time) ([]CategorySpend, error)
+}
+
+type TransactionRepository interface {
+	// Create stores a payment and sets its ID; Kind defaults to a payment
+	Create(transaction *model.Transactions) error
+	// CreateBatch stores several payments atomically, sets their IDs and
+	// links them with a BatchID
+	CreateBatch(transactions []model.Transactions) error
+	Get(transactionID int64) (*model.Transactions, error)
+	// Reverse marks a payment as reversed by userID instead of deleting it and
+	// returns the updated payment
+	Reverse(transactionID, userID int64) (*model.Transactions, error)
+	// ReverseBatch reverses every payment in a batch atomically and returns
+	// them
+	ReverseBatch(batchID, userID int64) ([]model.Transactions, error)
+	// ListByGroup returns the group's payments matching filter, newest first,
+	// including reversed ones
+	ListByGroup(groupID int64, filter TransactionFilter) ([]model.Transactions, error)
+}
+
+type SessionRepository interface {
+	Create(sessionID string, userID int64, expiresAt time.Time) error
+	// Get returns the user a session belongs to and when it expires
+	Get(sessionID string) (int64, time.Time, error)
+	Delete(sessionID string) error
+	DeleteExpired() error
+}
+
+type MemoryRepository interface {
+	Create(groupID int64, filename, imageURL string) (*model.Memory, error)
+	Get(memoryID int64) (*model.Memory, error)
+	ListByGroup(groupID int64) ([]model.Memory, error)
+	Delete(memoryID int64) error
+}
+
+type PasswordResetRepository interface {
+	Create(reset model.PasswordReset) error
+	// Latest returns the most recent reset requested for an email address
+	Latest(email string) (*model.PasswordReset, error)
+	MarkUsed(resetID int64) error
+}
+
+type ReminderRepository interface {
+	// StartJob records the start of a reminder job and returns its ID
+	StartJob(jobType, status string) (int64, error)
+	FinishJob(jobID int64, status, errorMsg string, success, failed int) error
+	// LogSent records that a reminder was sent to a user
+	LogSent(userID int64, reminderType string) error
+}
+
+type RateRepository interface {
+	// Save stores rates atomically, replacing values already stored for the
+	// same day and pair
+	Save(rates []ExchangeRate) error
+	// Find returns the latest rate published on or before date for base/quote
+	// or the opposite pair, preferring base/quote on the same day
+	Find(date, base, quote string) (*ExchangeRate, error)
+}
+
+// Repositories is the data access layer used by the HTTP handlers
+type Repositories struct {
+	Users          UserRepository
+	Groups         GroupRepository
+	Items          ItemRepository
+	Splits         SplitRepository
+	Transactions   TransactionRepository
+	Sessions       SessionRepository
+	Memories       MemoryRepository
+	PasswordResets PasswordResetRepository
+	Reminders      ReminderRepository
+	Recurring      RecurringExpenseRepository
+	Rates          RateRepository
+}
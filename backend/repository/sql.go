@@ -0,0 +1,1310 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-splitwise/model"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Dialect is the flavour of SQL spoken by a database
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DialectOf picks the dialect from the scheme of databaseURL. URLs without a
+// scheme are handed to lib/pq, which also understands key=value settings.
+func DialectOf(databaseURL string) (Dialect, error) {
+	scheme, _, found := strings.Cut(databaseURL, ":")
+	if !found || strings.Contains(scheme, "=") {
+		return Postgres, nil
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return Postgres, nil
+	case "sqlite", "sqlite3", "file":
+		return SQLite, nil
+	default:
+		return "", fmt.Errorf("unsupported database scheme %q", scheme)
+	}
+}
+
+// Open connects to the database at databaseURL with the driver for its scheme
+// and checks the connection works
+func Open(databaseURL string) (*sql.DB, Dialect, error) {
+	dialect, err := DialectOf(databaseURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var db *sql.DB
+	if dialect == SQLite {
+		db, err = openSQLite(databaseURL)
+	} else {
+		db, err = openPostgres(databaseURL)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Verify connections work -> health checkup
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, "", err
+	}
+	return db, dialect, nil
+}
+
+func openPostgres(databaseURL string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set explicit connection pool parameters
+	db.SetMaxOpenConns(20)                  // Limit total connections
+	db.SetMaxIdleConns(5)                   // Limit idle connections
+	db.SetConnMaxLifetime(30 * time.Minute) // Recycle connections periodically
+	db.SetConnMaxIdleTime(5 * time.Minute)  // Don't keep idle connections too long
+	return db, nil
+}
+
+// NewSQL creates repositories backed by a Postgres or SQLite database. The
+// queries stick to SQL both understand.
+func NewSQL(db *sql.DB) Repositories {
+	return Repositories{
+		Users:          &sqlUsers{db: db},
+		Groups:         &sqlGroups{db: db},
+		Items:          &sqlItems{db: db},
+		Splits:         &sqlSplits{db: db},
+		Transactions:   &sqlTransactions{db: db},
+		Sessions:       &sqlSessions{db: db},
+		Memories:       &sqlMemories{db: db},
+		PasswordResets: &sqlPasswordResets{db: db},
+		Reminders:      &sqlReminders{db: db},
+		Recurring:      &sqlRecurringExpenses{db: db},
+		Rates:          &sqlRates{db: db},
+	}
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// isDuplicate reports whether err is a uniqueness violation
+func isDuplicate(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return isSQLiteDuplicate(err)
+}
+
+// requireAffected returns ErrNotFound when result changed no rows
+func requireAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanUsers(rows *sql.Rows) ([]model.UserResponse, error) {
+	defer rows.Close()
+
+	var users []model.UserResponse
+	for rows.Next() {
+		var u model.UserResponse
+		if err := rows.Scan(&u.UserID, &u.Name, &u.Email); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+type sqlUsers struct {
+	db *sql.DB
+}
+
+func (r *sqlUsers) Create(name, email, passwordHash string) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow(`INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING user_id`,
+		name, email, passwordHash).Scan(&userID)
+	if isDuplicate(err) {
+		return 0, ErrDuplicate
+	}
+	return userID, err
+}
+
+func (r *sqlUsers) CreateWithGoogle(name, email, googleID string) (int64, error) {
+	var userID int64
+	err := r.db.QueryRow("INSERT INTO users (name, email, google_id) VALUES ($1, $2, $3) RETURNING user_id",
+		name, email, googleID).Scan(&userID)
+	return userID, err
+}
+
+func (r *sqlUsers) LinkGoogle(userID int64, googleID string) error {
+	_, err := r.db.Exec("UPDATE users SET google_id = $1 WHERE user_id = $2", googleID, userID)
+	return err
+}
+
+func (r *sqlUsers) Get(userID int64) (*model.UserResponse, error) {
+	user := &model.UserResponse{}
+	err := r.db.QueryRow("SELECT user_id, name, email FROM users WHERE user_id = $1", userID).Scan(
+		&user.UserID, &user.Name, &user.Email,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return user, nil
+}
+
+func (r *sqlUsers) GetByEmail(email string) (*model.UserResponse, error) {
+	user := &model.UserResponse{}
+	err := r.db.QueryRow("SELECT user_id, name, email, COALESCE(password, '') FROM users WHERE email = $1", email).Scan(
+		&user.UserID, &user.Name, &user.Email, &user.Password,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return user, nil
+}
+
+func (r *sqlUsers) GetByGoogleID(googleID string) (*model.UserResponse, error) {
+	user := &model.UserResponse{}
+	err := r.db.QueryRow("SELECT user_id, name, email FROM users WHERE google_id = $1", googleID).Scan(
+		&user.UserID, &user.Name, &user.Email,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return user, nil
+}
+
+func (r *sqlUsers) UpdatePassword(userID int64, passwordHash string) error {
+	result, err := r.db.Exec("UPDATE users SET password = $1 WHERE user_id = $2", passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (r *sqlUsers) UpdatePasswordByEmail(email, passwordHash string) error {
+	_, err := r.db.Exec("UPDATE users SET password = $1 WHERE email = $2", passwordHash, email)
+	return err
+}
+
+func (r *sqlUsers) List() ([]model.UserResponse, error) {
+	rows, err := r.db.Query("SELECT user_id, name, email FROM users")
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+func (r *sqlUsers) ListNotInGroup(groupID int64) ([]model.UserResponse, error) {
+	rows, err := r.db.Query(`SELECT u.user_id, u.name, u.email
+	FROM users u
+	WHERE u.user_id NOT IN (
+		SELECT user_id
+		FROM group_users
+		WHERE group_id = $1
+	)`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+type sqlGroups struct {
+	db *sql.DB
+}
+
+func (r *sqlGroups) Create(group *model.Group) error {
+	return r.db.QueryRow(`INSERT INTO groups (name, base_currency) VALUES ($1, $2) RETURNING group_id`,
+		group.GroupName, group.BaseCurrency).Scan(&group.GroupID)
+}
+
+func (r *sqlGroups) Get(groupID int64) (*model.Group, error) {
+	group := &model.Group{}
+	err := r.db.QueryRow("SELECT group_id, name, COALESCE(rounding_policy, ''), COALESCE(base_currency, '') FROM groups WHERE group_id = $1",
+		groupID).Scan(&group.GroupID, &group.GroupName, &group.RoundingPolicy, &group.BaseCurrency)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return group, nil
+}
+
+func (r *sqlGroups) ListByUser(userID int64) ([]model.Group, error) {
+	rows, err := r.db.Query("SELECT group_id, name, COALESCE(rounding_policy, ''), COALESCE(base_currency, '') FROM groups WHERE group_id IN (SELECT group_id FROM group_users WHERE user_id = $1)",
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []model.Group
+	for rows.Next() {
+		var g model.Group
+		if err := rows.Scan(&g.GroupID, &g.GroupName, &g.RoundingPolicy, &g.BaseCurrency); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (r *sqlGroups) SetRoundingPolicy(groupID int64, policy string) (*model.Group, error) {
+	group := &model.Group{}
+	err := r.db.QueryRow(`UPDATE groups SET rounding_policy = $1 WHERE group_id = $2
+	                      RETURNING group_id, name, COALESCE(rounding_policy, ''), COALESCE(base_currency, '')`,
+		policy, groupID).Scan(&group.GroupID, &group.GroupName, &group.RoundingPolicy, &group.BaseCurrency)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return group, nil
+}
+
+func (r *sqlGroups) SetBaseCurrency(groupID int64, currency string) (*model.Group, error) {
+	group := &model.Group{}
+	err := r.db.QueryRow(`UPDATE groups SET base_currency = $1 WHERE group_id = $2
+	                      RETURNING group_id, name, COALESCE(rounding_policy, ''), COALESCE(base_currency, '')`,
+		currency, groupID).Scan(&group.GroupID, &group.GroupName, &group.RoundingPolicy, &group.BaseCurrency)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return group, nil
+}
+
+func (r *sqlGroups) AddMember(groupID, userID int64) error {
+	query := `INSERT INTO group_users (group_id, user_id)
+	          VALUES ($1, $2)
+	          ON CONFLICT DO NOTHING`
+	_, err := r.db.Exec(query, groupID, userID)
+	return err
+}
+
+func (r *sqlGroups) IsMember(groupID, userID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM group_users WHERE group_id = $1 AND user_id = $2)",
+		groupID, userID,
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *sqlGroups) Members(groupID int64) ([]model.UserResponse, error) {
+	rows, err := r.db.Query(`
+		SELECT u.user_id, u.name, u.email
+		FROM users u
+		JOIN group_users gu ON u.user_id = gu.user_id
+		WHERE gu.group_id = $1`,
+		groupID)
+	if err != nil {
+		return nil, err
+	}
+	return scanUsers(rows)
+}
+
+func (r *sqlGroups) MemberIDs(groupID int64) ([]int64, error) {
+	rows, err := r.db.Query("SELECT user_id FROM group_users WHERE group_id = $1", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (r *sqlGroups) Categories(groupID int64) ([]string, error) {
+	rows, err := r.db.Query("SELECT name FROM group_categories WHERE group_id = $1 ORDER BY name", groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (r *sqlGroups) AddCategory(groupID int64, name string) error {
+	_, err := r.db.Exec("INSERT INTO group_categories (group_id, name) VALUES ($1, $2)", groupID, name)
+	if isDuplicate(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+type sqlItems struct {
+	db *sql.DB
+}
+
+// writeSplits runs split and stores the resulting shares, payers and rounding
+// of an expense as part of tx
+func writeSplits(tx *sql.Tx, expense *model.Expense, split SplitFunc) error {
+	if err := split(expense); err != nil {
+		return err
+	}
+
+	for _, share := range expense.Shares {
+		_, err := tx.Exec(`INSERT INTO item_splits (item_id, user_id, share, rounding_adjustment)
+		                   VALUES ($1, $2, $3, $4)
+		                   ON CONFLICT (item_id, user_id)
+		                   DO UPDATE SET share = EXCLUDED.share, rounding_adjustment = EXCLUDED.rounding_adjustment`,
+			expense.ExpenseID, share.UserID, share.ShareAmount, share.RoundingAdjustment)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM item_payers WHERE item_id = $1", expense.ExpenseID); err != nil {
+		return err
+	}
+	for _, payer := range expense.Payers {
+		_, err := tx.Exec("INSERT INTO item_payers (item_id, user_id, amount) VALUES ($1, $2, $3)",
+			expense.ExpenseID, payer.UserID, payer.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("UPDATE items SET rounding_policy = $1, rounding_remainder = $2 WHERE item_id = $3",
+		expense.RoundingPolicy, expense.RoundingRemainder, expense.ExpenseID)
+	return err
+}
+
+// insertItem stores a new expense and its splits as part of tx
+func insertItem(tx *sql.Tx, groupID int64, expense *model.Expense, split SplitFunc) error {
+	query := `INSERT INTO items (group_id, amount, paid_by, description, category, expense_date, currency, exchange_rate, exchange_rate_currency)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING item_id, created_at`
+	err := tx.QueryRow(query, groupID, expense.Amount, expense.PayerID, expense.Description, expense.Category, expense.ExpenseDate,
+		expense.Currency, expense.ExchangeRate, expense.ExchangeRateCurrency).Scan(&expense.ExpenseID, &expense.Created_at)
+	if err != nil {
+		return err
+	}
+	expense.GroupID = groupID
+
+	return writeSplits(tx, expense, split)
+}
+
+func (r *sqlItems) Create(groupID int64, expense *model.Expense, split SplitFunc) error {
+	// The item and all of its splits are written atomically so a failure can
+	// never leave an item with only some of its splits
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertItem(tx, groupID, expense, split); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlItems) Update(expense *model.Expense, split SplitFunc) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE items SET amount = $1, paid_by = $2, description = $3, category = $4, expense_date = $5,
+	          currency = $6, exchange_rate = $7, exchange_rate_currency = $8
+	          WHERE item_id = $9 RETURNING group_id, created_at`
+	err = tx.QueryRow(query, expense.Amount, expense.PayerID, expense.Description, expense.Category, expense.ExpenseDate,
+		expense.Currency, expense.ExchangeRate, expense.ExchangeRateCurrency, expense.ExpenseID).Scan(&expense.GroupID, &expense.Created_at)
+	if err != nil {
+		return notFound(err)
+	}
+
+	// Splits are recalculated from scratch; settlements are derived from them
+	if _, err := tx.Exec("DELETE FROM item_splits WHERE item_id = $1", expense.ExpenseID); err != nil {
+		return err
+	}
+
+	if err := writeSplits(tx, expense, split); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlItems) Delete(itemID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM item_splits WHERE item_id = $1", itemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM item_payers WHERE item_id = $1", itemID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM items WHERE item_id = $1", itemID)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const itemColumns = `item_id, group_id, amount, paid_by, description, category, created_at, expense_date,
+	COALESCE(rounding_policy, ''), COALESCE(rounding_remainder, 0),
+	COALESCE(currency, ''), COALESCE(exchange_rate, 0), COALESCE(exchange_rate_currency, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// rowQuerier is implemented by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func scanItem(row rowScanner, item *model.Expense) error {
+	var expenseDate time.Time
+	err := row.Scan(&item.ExpenseID, &item.GroupID, &item.Amount, &item.PayerID, &item.Description, &item.Category, &item.Created_at,
+		&expenseDate, &item.RoundingPolicy, &item.RoundingRemainder, &item.Currency, &item.ExchangeRate, &item.ExchangeRateCurrency)
+	if err != nil {
+		return err
+	}
+	item.ExpenseDate = expenseDate.Format(time.DateOnly)
+	return nil
+}
+
+func (r *sqlItems) Get(itemID int64) (*model.Expense, error) {
+	item := &model.Expense{}
+	err := scanItem(r.db.QueryRow("SELECT "+itemColumns+" FROM items WHERE item_id = $1", itemID), item)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return item, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *sqlItems) ListByGroup(groupID int64, filter ItemFilter) ([]model.Expense, error) {
+	args := []any{groupID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"group_id = $1"}
+	if filter.PayerID != 0 {
+		payer := arg(filter.PayerID)
+		conditions = append(conditions, "(paid_by = "+payer+" OR EXISTS (SELECT 1 FROM item_payers p WHERE p.item_id = items.item_id AND p.user_id = "+payer+"))")
+	}
+	if filter.ParticipantID != 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM item_splits s WHERE s.item_id = items.item_id AND s.user_id = "+arg(filter.ParticipantID)+")")
+	}
+	if filter.From != "" {
+		conditions = append(conditions, "expense_date >= "+arg(filter.From))
+	}
+	if filter.To != "" {
+		conditions = append(conditions, "expense_date <= "+arg(filter.To))
+	}
+	if filter.MinAmount != 0 {
+		conditions = append(conditions, "amount >= "+arg(filter.MinAmount))
+	}
+	if filter.MaxAmount != 0 {
+		conditions = append(conditions, "amount <= "+arg(filter.MaxAmount))
+	}
+	if filter.Category != nil {
+		conditions = append(conditions, "category = "+arg(*filter.Category))
+	}
+	for _, word := range strings.Fields(strings.ToLower(filter.Search)) {
+		conditions = append(conditions, "LOWER(description) LIKE "+arg("%"+escapeLike(word)+"%")+` ESCAPE '\'`)
+	}
+	if filter.After != nil {
+		date, itemID := arg(filter.After.ExpenseDate), arg(filter.After.ItemID)
+		conditions = append(conditions, "(expense_date < "+date+" OR (expense_date = "+date+" AND item_id < "+itemID+"))")
+	}
+
+	query := "SELECT " + itemColumns + " FROM items WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY expense_date DESC, item_id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.Expense
+	for rows.Next() {
+		var item model.Expense
+		if err := scanItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+type sqlRecurringExpenses struct {
+	db *sql.DB
+}
+
+const recurringColumns = `id, group_id, amount, payer_id, payers, description, category, expense_type, shares, currency,
+	cadence, start_at, next_run_at, last_run_at, active, last_error, created_by, created_at`
+
+func scanRecurring(row rowScanner, recurring *model.RecurringExpense) error {
+	var payers, shares string
+	var lastRunAt sql.NullTime
+	err := row.Scan(&recurring.ID, &recurring.GroupID, &recurring.Amount, &recurring.PayerID, &payers,
+		&recurring.Description, &recurring.Category, &recurring.ExpenseType, &shares, &recurring.Currency, &recurring.Cadence,
+		&recurring.StartAt, &recurring.NextRunAt, &lastRunAt, &recurring.Active, &recurring.LastError, &recurring.CreatedBy,
+		&recurring.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if lastRunAt.Valid {
+		recurring.LastRunAt = &lastRunAt.Time
+	}
+	if err := json.Unmarshal([]byte(payers), &recurring.Payers); err != nil {
+		return fmt.Errorf("invalid payers of recurring expense %d: %w", recurring.ID, err)
+	}
+	if err := json.Unmarshal([]byte(shares), &recurring.Shares); err != nil {
+		return fmt.Errorf("invalid shares of recurring expense %d: %w", recurring.ID, err)
+	}
+	return nil
+}
+
+func (r *sqlRecurringExpenses) list(query string, args ...any) ([]model.RecurringExpense, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recurring []model.RecurringExpense
+	for rows.Next() {
+		var rec model.RecurringExpense
+		if err := scanRecurring(rows, &rec); err != nil {
+			return nil, err
+		}
+		recurring = append(recurring, rec)
+	}
+	return recurring, rows.Err()
+}
+
+func (r *sqlRecurringExpenses) Create(recurring *model.RecurringExpense) error {
+	payers, err := json.Marshal(recurring.Payers)
+	if err != nil {
+		return err
+	}
+	shares, err := json.Marshal(recurring.Shares)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO recurring_expenses (group_id, amount, payer_id, payers, description, category, expense_type, shares,
+	          currency, cadence, start_at, next_run_at, active, created_by)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, created_at`
+	return r.db.QueryRow(query, recurring.GroupID, recurring.Amount, recurring.PayerID, string(payers),
+		recurring.Description, recurring.Category, recurring.ExpenseType, string(shares), recurring.Currency, recurring.Cadence,
+		recurring.StartAt.UTC(), recurring.NextRunAt.UTC(), recurring.Active, recurring.CreatedBy).Scan(
+		&recurring.ID, &recurring.CreatedAt)
+}
+
+func (r *sqlRecurringExpenses) Get(recurringID int64) (*model.RecurringExpense, error) {
+	recurring := &model.RecurringExpense{}
+	err := scanRecurring(r.db.QueryRow("SELECT "+recurringColumns+" FROM recurring_expenses WHERE id = $1", recurringID), recurring)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return recurring, nil
+}
+
+func (r *sqlRecurringExpenses) ListByGroup(groupID int64) ([]model.RecurringExpense, error) {
+	return r.list("SELECT "+recurringColumns+" FROM recurring_expenses WHERE group_id = $1 ORDER BY id", groupID)
+}
+
+func (r *sqlRecurringExpenses) Deactivate(recurringID int64) error {
+	result, err := r.db.Exec("UPDATE recurring_expenses SET active = FALSE WHERE id = $1", recurringID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (r *sqlRecurringExpenses) Fail(recurringID int64, reason string) error {
+	result, err := r.db.Exec("UPDATE recurring_expenses SET active = FALSE, last_error = $2 WHERE id = $1", recurringID, reason)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (r *sqlRecurringExpenses) Due(now time.Time) ([]model.RecurringExpense, error) {
+	return r.list("SELECT "+recurringColumns+" FROM recurring_expenses WHERE active AND next_run_at <= $1 ORDER BY next_run_at",
+		now.UTC())
+}
+
+func (r *sqlRecurringExpenses) Materialize(recurringID int64, scheduledFor, nextRunAt time.Time, expense *model.Expense, split SplitFunc) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Claiming the occurrence first makes posting it idempotent across
+	// restarts and across several instances running the scheduler
+	result, err := tx.Exec(`INSERT INTO recurring_expense_runs (recurring_expense_id, scheduled_for)
+	                        VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		recurringID, scheduledFor.UTC())
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if claimed > 0 {
+		if err := insertItem(tx, expense.GroupID, expense, split); err != nil {
+			return false, err
+		}
+		_, err := tx.Exec("UPDATE recurring_expense_runs SET item_id = $1 WHERE recurring_expense_id = $2 AND scheduled_for = $3",
+			expense.ExpenseID, recurringID, scheduledFor.UTC())
+		if err != nil {
+			return false, err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE recurring_expenses SET next_run_at = $1, last_run_at = $2
+	                  WHERE id = $3 AND next_run_at <= $2`,
+		nextRunAt.UTC(), scheduledFor.UTC(), recurringID)
+	if err != nil {
+		return false, err
+	}
+
+	return claimed > 0, tx.Commit()
+}
+
+type sqlSplits struct {
+	db *sql.DB
+}
+
+func (r *sqlSplits) ListByItem(itemID int64) ([]model.UserShare, error) {
+	rows, err := r.db.Query("SELECT user_id, share, COALESCE(rounding_adjustment, 0) FROM item_splits WHERE item_id = $1", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []model.UserShare
+	for rows.Next() {
+		var share model.UserShare
+		if err := rows.Scan(&share.UserID, &share.ShareAmount, &share.RoundingAdjustment); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (r *sqlSplits) Payers(itemID int64) ([]model.ExpensePayer, error) {
+	rows, err := r.db.Query("SELECT user_id, amount FROM item_payers WHERE item_id = $1", itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payers []model.ExpensePayer
+	for rows.Next() {
+		var payer model.ExpensePayer
+		if err := rows.Scan(&payer.UserID, &payer.Amount); err != nil {
+			return nil, err
+		}
+		payers = append(payers, payer)
+	}
+	return payers, rows.Err()
+}
+
+// GroupDebts computes every pairwise debt in a group in one aggregate query.
+// Each item's debtors and creditors are lined up in user ID order along the
+// item's total, using running sums of their shares, and every debtor owes
+// every creditor the stretch their parts have in common. That reduces to the
+// payer being owed every share when there is a single payer and needs no
+// rounding when there are several. Recorded transactions count as a debt from
+// the receiver back to the payer unless they were reversed. Amounts in another
+// currency are converted with the rate pinned on the item; the rare items
+// whose pinned rate doesn't match the base currency are left unconverted and
+// summed per currency.
+func (r *sqlSplits) GroupDebts(groupID int64, baseCurrency string) ([]Debt, error) {
+	rows, err := r.db.Query(`
+		WITH shares AS (
+			SELECT i.item_id, COALESCE(i.currency, '') AS currency, COALESCE(i.exchange_rate, 0) AS rate,
+			       COALESCE(i.exchange_rate_currency, '') AS rate_currency, s.user_id, s.share
+			FROM items i
+			JOIN item_splits s ON s.item_id = i.item_id
+			WHERE i.group_id = $1 AND s.share <> 0
+		),
+		debtors AS (
+			SELECT item_id, currency, rate, rate_currency, user_id,
+			       SUM(-share) OVER (PARTITION BY item_id ORDER BY user_id) AS end_at,
+			       SUM(-share) OVER (PARTITION BY item_id ORDER BY user_id) + share AS start_at
+			FROM shares
+			WHERE share < 0
+		),
+		creditors AS (
+			SELECT item_id, user_id,
+			       SUM(share) OVER (PARTITION BY item_id ORDER BY user_id) AS end_at,
+			       SUM(share) OVER (PARTITION BY item_id ORDER BY user_id) - share AS start_at
+			FROM shares
+			WHERE share > 0
+		),
+		item_debts AS (
+			SELECT d.user_id AS debtor_id, c.user_id AS creditor_id, d.currency, d.rate, d.rate_currency,
+			       CASE WHEN d.end_at < c.end_at THEN d.end_at ELSE c.end_at END
+			     - CASE WHEN d.start_at > c.start_at THEN d.start_at ELSE c.start_at END AS amount
+			FROM debtors d
+			JOIN creditors c ON c.item_id = d.item_id AND c.start_at < d.end_at AND d.start_at < c.end_at
+		)
+		SELECT debtor_id, creditor_id, currency,
+		       CAST(SUM(CASE
+		           WHEN currency IN ('', $2) THEN amount
+		           WHEN rate > 0 AND rate_currency = $2 THEN ROUND(CAST(amount AS NUMERIC) * CAST(rate AS NUMERIC))
+		           ELSE 0 END) AS BIGINT),
+		       CAST(SUM(CASE
+		           WHEN currency IN ('', $2) OR (rate > 0 AND rate_currency = $2) THEN 0
+		           ELSE amount END) AS BIGINT)
+		FROM item_debts
+		GROUP BY debtor_id, creditor_id, currency
+		UNION ALL
+		SELECT user_id, payer_id, '', CAST(SUM(amount) AS BIGINT), 0
+		FROM transactions
+		WHERE group_id = $1 AND reversed_at IS NULL
+		GROUP BY user_id, payer_id`,
+		groupID, baseCurrency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var debts []Debt
+	for rows.Next() {
+		var debt Debt
+		if err := rows.Scan(&debt.DebtorID, &debt.CreditorID, &debt.Currency, &debt.Converted, &debt.Unconverted); err != nil {
+			return nil, err
+		}
+		debts = append(debts, debt)
+	}
+	return debts, rows.Err()
+}
+
+// CategorySpend aggregates each user's payments and shares per category and
+// currency, converting like GroupDebts. What a user owes for an item is what
+// they paid minus their balance on it; items recorded before multiple payers
+// were supported count their whole amount as paid by paid_by.
+func (r *sqlSplits) CategorySpend(groupID int64, baseCurrency string, from, to time.Time) ([]CategorySpend, error) {
+	args := []any{groupID, baseCurrency}
+	var dateFilter string
+	if !from.IsZero() {
+		args = append(args, from.Format(time.DateOnly))
+		dateFilter += fmt.Sprintf(" AND i.expense_date >= $%d", len(args))
+	}
+	if !to.IsZero() {
+		args = append(args, to.Format(time.DateOnly))
+		dateFilter += fmt.Sprintf(" AND i.expense_date < $%d", len(args))
+	}
+
+	rows, err := r.db.Query(`
+		WITH spend AS (
+			SELECT COALESCE(i.category, '') AS category, s.user_id,
+			       COALESCE(i.currency, '') AS currency,
+			       COALESCE(i.exchange_rate, 0) AS rate,
+			       COALESCE(i.exchange_rate_currency, '') AS rate_currency,
+			       COALESCE(p.amount, CASE
+			           WHEN i.paid_by = s.user_id AND NOT EXISTS (SELECT 1 FROM item_payers x WHERE x.item_id = i.item_id)
+			           THEN i.amount ELSE 0 END) AS paid,
+			       s.share
+			FROM items i
+			JOIN item_splits s ON s.item_id = i.item_id
+			LEFT JOIN item_payers p ON p.item_id = i.item_id AND p.user_id = s.user_id
+			WHERE i.group_id = $1`+dateFilter+`
+		)
+		SELECT category, user_id, currency,
+		       CAST(SUM(CASE
+		           WHEN currency IN ('', $2) THEN paid
+		           WHEN rate > 0 AND rate_currency = $2 THEN ROUND(CAST(paid AS NUMERIC) * CAST(rate AS NUMERIC))
+		           ELSE 0 END) AS BIGINT),
+		       CAST(SUM(CASE
+		           WHEN currency IN ('', $2) THEN paid - share
+		           WHEN rate > 0 AND rate_currency = $2 THEN ROUND(CAST(paid - share AS NUMERIC) * CAST(rate AS NUMERIC))
+		           ELSE 0 END) AS BIGINT),
+		       CAST(SUM(CASE
+		           WHEN currency IN ('', $2) OR (rate > 0 AND rate_currency = $2) THEN 0
+		           ELSE paid END) AS BIGINT),
+		       CAST(SUM(CASE
+		           WHEN currency IN ('', $2) OR (rate > 0 AND rate_currency = $2) THEN 0
+		           ELSE paid - share END) AS BIGINT)
+		FROM spend
+		GROUP BY category, user_id, currency`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spend []CategorySpend
+	for rows.Next() {
+		var row CategorySpend
+		err := rows.Scan(&row.Category, &row.UserID, &row.Currency, &row.Paid, &row.Owed, &row.UnconvertedPaid, &row.UnconvertedOwed)
+		if err != nil {
+			return nil, err
+		}
+		spend = append(spend, row)
+	}
+	return spend, rows.Err()
+}
+
+type sqlTransactions struct {
+	db *sql.DB
+}
+
+// insertTransaction stores transaction with db or a transaction on it
+func insertTransaction(db rowQuerier, transaction *model.Transactions) error {
+	if transaction.Kind == "" {
+		transaction.Kind = model.TransactionPayment
+	}
+	query := `INSERT INTO transactions (user_id, payer_id, group_id, amount, kind, batch_id)
+	          VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0)) RETURNING id, created_at`
+	return db.QueryRow(query, transaction.UserID, transaction.PayerID, transaction.GroupID, transaction.Amount, transaction.Kind,
+		transaction.BatchID).Scan(&transaction.ID, &transaction.CreatedAt)
+}
+
+func (r *sqlTransactions) Create(transaction *model.Transactions) error {
+	return insertTransaction(r.db, transaction)
+}
+
+func (r *sqlTransactions) CreateBatch(transactions []model.Transactions) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The batch is identified by the ID of its first transaction, which is
+	// only known once it is stored
+	for i := range transactions {
+		if i > 0 {
+			transactions[i].BatchID = transactions[0].ID
+		}
+		if err := insertTransaction(tx, &transactions[i]); err != nil {
+			return err
+		}
+		if i == 0 {
+			transactions[0].BatchID = transactions[0].ID
+			if _, err := tx.Exec("UPDATE transactions SET batch_id = id WHERE id = $1", transactions[0].ID); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+const transactionColumns = "id, user_id, payer_id, group_id, amount, kind, created_at, reversed_at, COALESCE(reversed_by, 0), COALESCE(batch_id, 0)"
+
+func scanTransaction(row rowScanner, t *model.Transactions) error {
+	var reversedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.PayerID, &t.GroupID, &t.Amount, &t.Kind, &t.CreatedAt, &reversedAt, &t.ReversedBy, &t.BatchID); err != nil {
+		return err
+	}
+	if reversedAt.Valid {
+		t.ReversedAt = &reversedAt.Time
+	}
+	return nil
+}
+
+func (r *sqlTransactions) Get(transactionID int64) (*model.Transactions, error) {
+	transaction := &model.Transactions{}
+	err := scanTransaction(r.db.QueryRow("SELECT "+transactionColumns+" FROM transactions WHERE id = $1", transactionID), transaction)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return transaction, nil
+}
+
+func (r *sqlTransactions) Reverse(transactionID, userID int64) (*model.Transactions, error) {
+	transaction := &model.Transactions{}
+	err := scanTransaction(r.db.QueryRow(`
+		UPDATE transactions SET reversed_at = $1, reversed_by = $2
+		WHERE id = $3 AND reversed_at IS NULL
+		RETURNING `+transactionColumns,
+		time.Now().UTC(), userID, transactionID), transaction)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Tell a missing transaction apart from one that was already reversed
+		if _, err := r.Get(transactionID); err != nil {
+			return nil, err
+		}
+		return nil, ErrAlreadyReversed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (r *sqlTransactions) ReverseBatch(batchID, userID int64) ([]model.Transactions, error) {
+	rows, err := r.db.Query(`
+		UPDATE transactions SET reversed_at = $1, reversed_by = $2
+		WHERE batch_id = $3 AND reversed_at IS NULL
+		RETURNING `+transactionColumns,
+		time.Now().UTC(), userID, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []model.Transactions
+	for rows.Next() {
+		var t model.Transactions
+		if err := scanTransaction(rows, &t); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(transactions) == 0 {
+		var exists bool
+		err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM transactions WHERE batch_id = $1)", batchID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrNotFound
+		}
+		return nil, ErrAlreadyReversed
+	}
+	sort.Slice(transactions, func(a, b int) bool { return transactions[a].ID < transactions[b].ID })
+	return transactions, nil
+}
+
+// ListByGroup orders by ID, which follows the order payments were recorded in
+// and gives pages a stable position to continue from
+func (r *sqlTransactions) ListByGroup(groupID int64, filter TransactionFilter) ([]model.Transactions, error) {
+	args := []any{groupID}
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"group_id = $1"}
+	if filter.PayerID != 0 {
+		conditions = append(conditions, "payer_id = "+arg(filter.PayerID))
+	}
+	if filter.ReceiverID != 0 {
+		conditions = append(conditions, "user_id = "+arg(filter.ReceiverID))
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.From.UTC()))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.To.UTC()))
+	}
+	if filter.BeforeID != 0 {
+		conditions = append(conditions, "id < "+arg(filter.BeforeID))
+	}
+
+	query := "SELECT " + transactionColumns + " FROM transactions WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []model.Transactions
+	for rows.Next() {
+		var t model.Transactions
+		if err := scanTransaction(rows, &t); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+type sqlSessions struct {
+	db *sql.DB
+}
+
+func (r *sqlSessions) Create(sessionID string, userID int64, expiresAt time.Time) error {
+	_, err := r.db.Exec(
+		"INSERT INTO sessions (session_id, user_id, expires_at) VALUES ($1, $2, $3)",
+		sessionID, userID, expiresAt.UTC(),
+	)
+	return err
+}
+
+func (r *sqlSessions) Get(sessionID string) (int64, time.Time, error) {
+	var userID int64
+	var expiresAt time.Time
+	err := r.db.QueryRow(
+		"SELECT user_id, expires_at FROM sessions WHERE session_id = $1",
+		sessionID,
+	).Scan(&userID, &expiresAt)
+	if err != nil {
+		return 0, time.Time{}, notFound(err)
+	}
+	return userID, expiresAt, nil
+}
+
+func (r *sqlSessions) Delete(sessionID string) error {
+	_, err := r.db.Exec("DELETE FROM sessions WHERE session_id = $1", sessionID)
+	return err
+}
+
+func (r *sqlSessions) DeleteExpired() error {
+	_, err := r.db.Exec("DELETE FROM sessions WHERE expires_at < $1", time.Now().UTC())
+	return err
+}
+
+type sqlMemories struct {
+	db *sql.DB
+}
+
+func (r *sqlMemories) Create(groupID int64, filename, imageURL string) (*model.Memory, error) {
+	memory := &model.Memory{}
+	err := r.db.QueryRow(`
+		INSERT INTO memories (group_id, filename, image_url, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, group_id, filename, image_url, created_at`,
+		groupID, filename, imageURL, time.Now().UTC()).Scan(
+		&memory.ID,
+		&memory.GroupID,
+		&memory.Filename,
+		&memory.ImageURL,
+		&memory.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return memory, nil
+}
+
+func (r *sqlMemories) Get(memoryID int64) (*model.Memory, error) {
+	memory := &model.Memory{}
+	err := r.db.QueryRow("SELECT id, group_id, filename, image_url, created_at FROM memories WHERE id = $1", memoryID).Scan(
+		&memory.ID,
+		&memory.GroupID,
+		&memory.Filename,
+		&memory.ImageURL,
+		&memory.CreatedAt,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return memory, nil
+}
+
+func (r *sqlMemories) ListByGroup(groupID int64) ([]model.Memory, error) {
+	rows, err := r.db.Query(`
+		SELECT id, group_id, filename, image_url, created_at
+		FROM memories
+		WHERE group_id = $1
+		ORDER BY created_at DESC`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var memories []model.Memory
+	for rows.Next() {
+		var memory model.Memory
+		err := rows.Scan(
+			&memory.ID,
+			&memory.GroupID,
+			&memory.Filename,
+			&memory.ImageURL,
+			&memory.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		memories = append(memories, memory)
+	}
+	return memories, rows.Err()
+}
+
+func (r *sqlMemories) Delete(memoryID int64) error {
+	result, err := r.db.Exec("DELETE FROM memories WHERE id = $1", memoryID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+type sqlPasswordResets struct {
+	db *sql.DB
+}
+
+func (r *sqlPasswordResets) Create(reset model.PasswordReset) error {
+	query := `
+		INSERT INTO password_resets (user_id, email, code, expires_at, used)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.Exec(query, reset.UserID, reset.Email, reset.Code, reset.ExpiresAt.UTC(), reset.Used)
+	return err
+}
+
+func (r *sqlPasswordResets) Latest(email string) (*model.PasswordReset, error) {
+	reset := &model.PasswordReset{}
+	query := `
+		SELECT id, user_id, email, code, expires_at, used, created_at
+		FROM password_resets
+		WHERE email = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	err := r.db.QueryRow(query, email).Scan(
+		&reset.ID, &reset.UserID, &reset.Email, &reset.Code,
+		&reset.ExpiresAt, &reset.Used, &reset.CreatedAt,
+	)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return reset, nil
+}
+
+func (r *sqlPasswordResets) MarkUsed(resetID int64) error {
+	_, err := r.db.Exec("UPDATE password_resets SET used = true WHERE id = $1", resetID)
+	return err
+}
+
+type sqlReminders struct {
+	db *sql.DB
+}
+
+func (r *sqlReminders) StartJob(jobType, status string) (int64, error) {
+	query := `
+		INSERT INTO reminder_jobs (job_type, status, started_at)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`
+
+	var jobID int64
+	err := r.db.QueryRow(query, jobType, status, time.Now().UTC()).Scan(&jobID)
+	return jobID, err
+}
+
+func (r *sqlReminders) FinishJob(jobID int64, status, errorMsg string, success, failed int) error {
+	query := `
+		UPDATE reminder_jobs
+		SET status = $2, error = $3, success_count = $4, error_count = $5, completed_at = $6
+		WHERE id = $1
+	`
+
+	_, err := r.db.Exec(query, jobID, status, errorMsg, success, failed, time.Now().UTC())
+	return err
+}
+
+func (r *sqlReminders) LogSent(userID int64, reminderType string) error {
+	query := `
+		INSERT INTO reminder_logs (user_id, reminder_type, sent_at)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := r.db.Exec(query, userID, reminderType, time.Now().UTC())
+	return err
+}
+
+type sqlRates struct {
+	db *sql.DB
+}
+
+func (r *sqlRates) Save(rates []ExchangeRate) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, rate := range rates {
+		_, err := tx.Exec(`
+			INSERT INTO exchange_rates (rate_date, base_currency, quote_currency, rate)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (rate_date, base_currency, quote_currency)
+			DO UPDATE SET rate = EXCLUDED.rate`,
+			rate.Date, rate.Base, rate.Quote, rate.Rate)
+		if err != nil {
+			return fmt.Errorf("failed to save rate %s/%s: %w", rate.Base, rate.Quote, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *sqlRates) Find(date, base, quote string) (*ExchangeRate, error) {
+	// rate_date is a DATE in Postgres but TEXT in SQLite, so it is read as
+	// text and trimmed to the day
+	var rate ExchangeRate
+	var rateDate string
+	err := r.db.QueryRow(`
+		SELECT rate_date, base_currency, quote_currency, rate
+		FROM exchange_rates
+		WHERE ((base_currency = $1 AND quote_currency = $2)
+		    OR (base_currency = $2 AND quote_currency = $1))
+		  AND rate_date <= $3
+		ORDER BY rate_date DESC, base_currency = $1 DESC
+		LIMIT 1`,
+		base, quote, date).Scan(&rateDate, &rate.Base, &rate.Quote, &rate.Rate)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	rate.Date, _, _ = strings.Cut(rateDate, "T")
+	return &rate, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// openSQLite opens the database file named by a sqlite://path, sqlite:path or
+// file:path URL; sqlite::memory: gives a throwaway in-memory database
+func openSQLite(databaseURL string) (*sql.DB, error) {
+	dsn := databaseURL
+	if !strings.HasPrefix(dsn, "file:") {
+		_, dsn, _ = strings.Cut(dsn, ":")
+		dsn = "file:" + strings.TrimPrefix(dsn, "//")
+	}
+
+	// Timestamps are stored as sortable text and foreign keys are enforced
+	// like they are in Postgres
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	dsn += separator + "_time_format=sqlite&_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, and every connection to an in-memory
+	// database would otherwise get a database of its own
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// isSQLiteDuplicate reports whether err is a SQLite uniqueness violation
+func isSQLiteDuplicate(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
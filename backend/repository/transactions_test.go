@@ -0,0 +1,191 @@
+package repository_test
+
+import (
+	"errors"
+	"go-splitwise/model"
+	"go-splitwise/repository"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCreateBatchLinksTransactions(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 2)
+
+			payment := model.Transactions{GroupID: groupID, PayerID: users[0], UserID: users[1], Amount: 10}
+			if err := repos.Transactions.Create(&payment); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			batch := []model.Transactions{
+				{GroupID: groupID, PayerID: users[0], UserID: users[1], Amount: 20, Kind: model.TransactionNetting},
+				{GroupID: groupID, PayerID: users[1], UserID: users[0], Amount: 30, Kind: model.TransactionNetting},
+			}
+			if err := repos.Transactions.CreateBatch(batch); err != nil {
+				t.Fatalf("CreateBatch: %v", err)
+			}
+
+			stored, err := repos.Transactions.ListByGroup(groupID, repository.TransactionFilter{})
+			if err != nil {
+				t.Fatalf("ListByGroup: %v", err)
+			}
+			batchIDs := make(map[int64]int64)
+			for _, transaction := range stored {
+				batchIDs[transaction.ID] = transaction.BatchID
+			}
+
+			if batchIDs[payment.ID] != 0 {
+				t.Errorf("single payment has batch %d", batchIDs[payment.ID])
+			}
+			for _, transaction := range batch {
+				if transaction.BatchID != batch[0].ID {
+					t.Errorf("transaction %d was given batch %d, want %d", transaction.ID, transaction.BatchID, batch[0].ID)
+				}
+				if batchIDs[transaction.ID] != batch[0].ID {
+					t.Errorf("transaction %d is stored in batch %d, want %d", transaction.ID, batchIDs[transaction.ID], batch[0].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestReverseBatch(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 2)
+
+			payment := model.Transactions{GroupID: groupID, PayerID: users[0], UserID: users[1], Amount: 10}
+			if err := repos.Transactions.Create(&payment); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			batch := []model.Transactions{
+				{GroupID: groupID, PayerID: users[0], UserID: users[1], Amount: 20, Kind: model.TransactionNetting},
+				{GroupID: groupID, PayerID: users[1], UserID: users[0], Amount: 30, Kind: model.TransactionNetting},
+			}
+			if err := repos.Transactions.CreateBatch(batch); err != nil {
+				t.Fatalf("CreateBatch: %v", err)
+			}
+
+			reversed, err := repos.Transactions.ReverseBatch(batch[0].BatchID, users[1])
+			if err != nil {
+				t.Fatalf("ReverseBatch: %v", err)
+			}
+			if len(reversed) != len(batch) {
+				t.Fatalf("reversed %d transactions, want %d", len(reversed), len(batch))
+			}
+			for i, transaction := range reversed {
+				if transaction.ID != batch[i].ID || transaction.ReversedAt == nil || transaction.ReversedBy != users[1] {
+					t.Errorf("reversed %+v, want transaction %d reversed by %d", transaction, batch[i].ID, users[1])
+				}
+			}
+
+			stored, err := repos.Transactions.Get(payment.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if stored.ReversedAt != nil {
+				t.Errorf("payment outside the batch was reversed")
+			}
+
+			if _, err := repos.Transactions.ReverseBatch(batch[0].BatchID, users[0]); !errors.Is(err, repository.ErrAlreadyReversed) {
+				t.Errorf("reversing again: %v, want %v", err, repository.ErrAlreadyReversed)
+			}
+			if _, err := repos.Transactions.ReverseBatch(batch[0].BatchID+100, users[0]); !errors.Is(err, repository.ErrNotFound) {
+				t.Errorf("reversing a missing batch: %v, want %v", err, repository.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	for name, repos := range testRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			groupID, users := createGroup(t, repos, 3)
+			a, b, c := users[0], users[1], users[2]
+
+			other := model.Group{GroupName: "Trip"}
+			if err := repos.Groups.Create(&other); err != nil {
+				t.Fatalf("create group: %v", err)
+			}
+			elsewhere := model.Transactions{GroupID: other.GroupID, PayerID: b, UserID: a, Amount: 5}
+			if err := repos.Transactions.Create(&elsewhere); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			var ids []int64
+			for _, transaction := range []model.Transactions{
+				{GroupID: groupID, PayerID: b, UserID: a, Amount: 100},
+				{GroupID: groupID, PayerID: c, UserID: a, Amount: 200},
+				{GroupID: groupID, PayerID: b, UserID: c, Amount: 300},
+				{GroupID: groupID, PayerID: a, UserID: b, Amount: 400},
+			} {
+				if err := repos.Transactions.Create(&transaction); err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+				ids = append(ids, transaction.ID)
+			}
+			batch := []model.Transactions{
+				{GroupID: groupID, PayerID: c, UserID: b, Amount: 50, Kind: model.TransactionNetting},
+				{GroupID: other.GroupID, PayerID: b, UserID: c, Amount: 50, Kind: model.TransactionNetting},
+			}
+			if err := repos.Transactions.CreateBatch(batch); err != nil {
+				t.Fatalf("CreateBatch: %v", err)
+			}
+			ids = append(ids, batch[0].ID)
+			if _, err := repos.Transactions.Reverse(ids[1], a); err != nil {
+				t.Fatalf("Reverse: %v", err)
+			}
+
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			tests := []struct {
+				name   string
+				filter repository.TransactionFilter
+				want   []int64
+			}{
+				{"newest first, including reversed ones", repository.TransactionFilter{}, []int64{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+				{"payer", repository.TransactionFilter{PayerID: b}, []int64{ids[2], ids[0]}},
+				{"receiver", repository.TransactionFilter{ReceiverID: a}, []int64{ids[1], ids[0]}},
+				{"payer and receiver", repository.TransactionFilter{PayerID: c, ReceiverID: b}, []int64{ids[4]}},
+				{"recorded in range", repository.TransactionFilter{From: today.AddDate(0, 0, -1), To: today.AddDate(0, 0, 2)}, []int64{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+				{"recorded before the range", repository.TransactionFilter{From: today.AddDate(0, 0, 2)}, []int64{}},
+				{"recorded after the range", repository.TransactionFilter{To: today.AddDate(0, 0, -1)}, []int64{}},
+				{"limit", repository.TransactionFilter{Limit: 2}, []int64{ids[4], ids[3]}},
+				{"continuing a page", repository.TransactionFilter{BeforeID: ids[3], Limit: 2}, []int64{ids[2], ids[1]}},
+				{"continuing a filtered page", repository.TransactionFilter{ReceiverID: a, BeforeID: ids[1]}, []int64{ids[0]}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					transactions, err := repos.Transactions.ListByGroup(groupID, tt.filter)
+					if err != nil {
+						t.Fatalf("ListByGroup: %v", err)
+					}
+					got := []int64{}
+					for _, transaction := range transactions {
+						got = append(got, transaction.ID)
+					}
+					if !reflect.DeepEqual(got, tt.want) {
+						t.Errorf("got transactions %v, want %v", got, tt.want)
+					}
+				})
+			}
+
+			transactions, err := repos.Transactions.ListByGroup(groupID, repository.TransactionFilter{})
+			if err != nil {
+				t.Fatalf("ListByGroup: %v", err)
+			}
+			for _, transaction := range transactions {
+				wantKind := model.TransactionPayment
+				if transaction.ID == ids[4] {
+					wantKind = model.TransactionNetting
+				}
+				if transaction.Kind != wantKind {
+					t.Errorf("transaction %d is a %q, want %q", transaction.ID, transaction.Kind, wantKind)
+				}
+				if reversed := transaction.ReversedAt != nil; reversed != (transaction.ID == ids[1]) {
+					t.Errorf("transaction %d reversed = %v", transaction.ID, reversed)
+				}
+			}
+		})
+	}
+}
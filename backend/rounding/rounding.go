@@ -0,0 +1,149 @@
+package rounding
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// Policy decides who receives the units left over when an amount cannot be
+// divided exactly between users
+type Policy string
+
+const (
+	// LargestRemainder gives leftover units to the users whose exact share was
+	// rounded down the most, lowest user ID first on ties
+	LargestRemainder Policy = "LARGEST_REMAINDER"
+	// RoundRobin hands leftover units out one at a time in user ID order,
+	// starting at a position that rotates from one expense to the next
+	RoundRobin Policy = "ROUND_ROBIN"
+	// PayerAbsorbs charges every leftover unit to the payer
+	PayerAbsorbs Policy = "PAYER_ABSORBS"
+)
+
+// Default is the policy of groups that never chose one
+const Default = PayerAbsorbs
+
+// IsValid reports whether p is a known policy
+func IsValid(p Policy) bool {
+	switch p {
+	case LargestRemainder, RoundRobin, PayerAbsorbs:
+		return true
+	}
+	return false
+}
+
+// Part is a user's weight in an allocation
+type Part struct {
+	UserID int64
+	Weight int64
+}
+
+// Allocation is a user's share of an amount. Rounding holds the units the
+// policy added on top of the user's rounded-down exact share.
+type Allocation struct {
+	UserID   int64
+	Amount   int64
+	Rounding int64
+}
+
+// Allocate divides total between parts in proportion to their weights, which
+// must be non-negative with a positive sum that fits in an int64. The allocations always add up to
+// total; the second return value is the number of leftover units handed out by
+// the policy. seed rotates the starting user for RoundRobin. A negative total
+// is allocated like its absolute value with every amount negated.
+func Allocate(policy Policy, total int64, parts []Part, payerID int64, seed int64) ([]Allocation, int64) {
+	if total < 0 {
+		allocations, leftover := Allocate(policy, -total, parts, payerID, seed)
+		for i := range allocations {
+			allocations[i].Amount = -allocations[i].Amount
+			allocations[i].Rounding = -allocations[i].Rounding
+		}
+		return allocations, -leftover
+	}
+
+	var totalWeight int64 = 0
+	for _, part := range parts {
+		totalWeight += part.Weight
+	}
+
+	allocations := make([]Allocation, len(parts))
+	remainders := make([]int64, len(parts))
+	var allocated int64 = 0
+	for i, part := range parts {
+		amount, remainder := mulDiv(total, part.Weight, totalWeight)
+		allocations[i] = Allocation{UserID: part.UserID, Amount: amount}
+		remainders[i] = remainder
+		allocated += amount
+	}
+
+	leftover := total - allocated
+	if leftover == 0 {
+		return allocations, 0
+	}
+
+	switch policy {
+	case LargestRemainder:
+		order := sortedIndexes(parts, func(a, b int) bool {
+			if remainders[a] != remainders[b] {
+				return remainders[a] > remainders[b]
+			}
+			return parts[a].UserID < parts[b].UserID
+		})
+		for i := int64(0); i < leftover; i++ {
+			allocations[order[i]].Amount++
+			allocations[order[i]].Rounding++
+		}
+	case RoundRobin:
+		var order []int
+		for _, i := range sortedIndexes(parts, func(a, b int) bool { return parts[a].UserID < parts[b].UserID }) {
+			if parts[i].Weight > 0 {
+				order = append(order, i)
+			}
+		}
+		start := int(seed % int64(len(order)))
+		if start < 0 {
+			start += len(order)
+		}
+		for i := int64(0); i < leftover; i++ {
+			idx := order[(start+int(i))%len(order)]
+			allocations[idx].Amount++
+			allocations[idx].Rounding++
+		}
+	default:
+		payerIdx := -1
+		for i, part := range parts {
+			if part.UserID == payerID {
+				payerIdx = i
+				break
+			}
+		}
+		if payerIdx == -1 {
+			allocations = append(allocations, Allocation{UserID: payerID})
+			payerIdx = len(allocations) - 1
+		}
+		allocations[payerIdx].Amount += leftover
+		allocations[payerIdx].Rounding += leftover
+	}
+
+	return allocations, leftover
+}
+
+// mulDiv returns a*b/c and a*b%c for non-negative a and b with b <= c. The
+// product is taken in 128 bits so large amounts and weights can't overflow,
+// and the quotient is at most a so it fits again.
+func mulDiv(a, b, c int64) (int64, int64) {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	quotient, remainder := bits.Div64(hi, lo, uint64(c))
+	return int64(quotient), int64(remainder)
+}
+
+func sortedIndexes(parts []Part, less func(a, b int) bool) []int {
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return less(order[a], order[b])
+	})
+	return order
+}
@@ -0,0 +1,57 @@
+package rounding
+
+import "testing"
+
+func TestAllocateSumsToTotal(t *testing.T) {
+	parts := []Part{{UserID: 1, Weight: 1}, {UserID: 2, Weight: 1}, {UserID: 3, Weight: 1}}
+
+	for _, policy := range []Policy{LargestRemainder, RoundRobin, PayerAbsorbs} {
+		for _, total := range []int64{100, -100, 1, -1, 0} {
+			allocations, leftover := Allocate(policy, total, parts, 1, 7)
+
+			var sum, rounding int64
+			for _, allocation := range allocations {
+				sum += allocation.Amount
+				rounding += allocation.Rounding
+			}
+			if sum != total {
+				t.Errorf("%s total %d: allocations sum to %d", policy, total, sum)
+			}
+			if rounding != leftover {
+				t.Errorf("%s total %d: rounding sums to %d, leftover is %d", policy, total, rounding, leftover)
+			}
+		}
+	}
+}
+
+func TestAllocateNegativeMirrorsPositive(t *testing.T) {
+	parts := []Part{{UserID: 1, Weight: 1}, {UserID: 2, Weight: 1}, {UserID: 3, Weight: 1}}
+
+	positive, _ := Allocate(LargestRemainder, 100, parts, 1, 0)
+	negative, leftover := Allocate(LargestRemainder, -100, parts, 1, 0)
+	if leftover != -1 {
+		t.Fatalf("leftover = %d, want -1", leftover)
+	}
+	for i := range positive {
+		if negative[i].Amount != -positive[i].Amount {
+			t.Errorf("user %d: got %d, want %d", negative[i].UserID, negative[i].Amount, -positive[i].Amount)
+		}
+	}
+}
+
+func TestAllocateLargeAmountsAndWeights(t *testing.T) {
+	// Each total*weight product is far beyond an int64
+	const total = 9_000_000_000_000_001
+	parts := []Part{{UserID: 1, Weight: 1_000_000_000_000}, {UserID: 2, Weight: 2_000_000_000_000}}
+
+	allocations, leftover := Allocate(LargestRemainder, total, parts, 1, 0)
+	if leftover != 1 {
+		t.Errorf("leftover = %d, want 1", leftover)
+	}
+	want := []int64{3_000_000_000_000_000, 6_000_000_000_000_001}
+	for i, allocation := range allocations {
+		if allocation.Amount != want[i] {
+			t.Errorf("user %d: got %d, want %d", allocation.UserID, allocation.Amount, want[i])
+		}
+	}
+}
@@ -6,32 +6,53 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func Router() *mux.Router {
+func Router(server *controller.Server) *mux.Router {
 	r := mux.NewRouter()
 
-	r.HandleFunc("/api/register", controller.RegisterUser).Methods("POST")
-	r.HandleFunc("/api/login", controller.LoginUser).Methods("POST")
-	r.HandleFunc("/api/groupdetails/{userId}", controller.GetGroupDetailsByUserId).Methods("GET")
-	r.HandleFunc("/api/creategroup/{userId}", controller.CreateGroup).Methods("POST")
-	r.HandleFunc("/api/addUsersToGroup/{groupId}", controller.AddUsersToGroup).Methods("POST")
-	r.HandleFunc("/api/groupUsers/{groupId}", controller.GetGroupUsers).Methods("GET")
-	r.HandleFunc("/api/notGroupUsers/{groupId}", controller.GetNotGroupUsers).Methods("GET")
-	r.HandleFunc("/api/addExpense/{groupId}", controller.AddExpense).Methods("POST")
-	r.HandleFunc("/api/items/{groupId}", controller.GetItemsByGroupId).Methods("GET")
-	r.HandleFunc("/api/settlements/{groupId}/{userId}", controller.GetSettlements).Methods("POST")
-	r.HandleFunc("/api/auth/google", controller.HandleGoogleAuth).Methods("POST")
-	r.HandleFunc("/api/me", controller.GetLoggedInUser).Methods("GET")
-	r.HandleFunc("/api/logout", controller.Logout).Methods("POST")
-	r.HandleFunc("/api/update-password", controller.UpdatePassword).Methods("POST")
-	r.HandleFunc("/api/auth/request-password-reset", controller.RequestPasswordResetHandler).Methods("POST")
-	r.HandleFunc("/api/auth/reset-password-complete", controller.ResetPasswordCompleteHandler).Methods("POST")
-	r.HandleFunc("/api/memories/{groupId}", controller.GetMemoriesHandler).Methods("GET")
-	r.HandleFunc("/api/memories/upload", controller.UploadMemoryHandler).Methods("POST")
-	r.HandleFunc("/api/memories/{memoryId}", controller.DeleteMemoryHandler).Methods("DELETE")
-	r.HandleFunc("/api/getTransactions/{groupId}", controller.GetTransactions).Methods("GET")
-	r.HandleFunc("/api/insertTransactions/{groupId}", controller.InsertTransactions).Methods("POST")
-	r.HandleFunc("/api/trigger-monthly-reminders", controller.TriggerMonthlyReminders).Methods("POST")
-	r.HandleFunc("/api/wakeup", controller.Ping).Methods("GET")
+	r.HandleFunc("/api/register", server.RegisterUser).Methods("POST")
+	r.HandleFunc("/api/login", server.LoginUser).Methods("POST")
+	r.HandleFunc("/api/auth/google", server.HandleGoogleAuth).Methods("POST")
+	r.HandleFunc("/api/me", server.GetLoggedInUser).Methods("GET")
+	r.HandleFunc("/api/logout", server.Logout).Methods("POST")
+	r.HandleFunc("/api/update-password", server.UpdatePassword).Methods("POST")
+	r.HandleFunc("/api/auth/request-password-reset", server.RequestPasswordResetHandler).Methods("POST")
+	r.HandleFunc("/api/auth/reset-password-complete", server.ResetPasswordCompleteHandler).Methods("POST")
+	r.HandleFunc("/api/trigger-monthly-reminders", server.TriggerMonthlyReminders).Methods("POST")
+	r.HandleFunc("/api/rates", server.UploadExchangeRates).Methods("POST")
+	r.HandleFunc("/api/wakeup", server.Ping).Methods("GET")
+
+	// Routes below require a valid session and membership of any group they reference
+	s := r.NewRoute().Subrouter()
+	s.Use(server.RequireSession)
+
+	s.HandleFunc("/api/groupdetails/{userId}", server.GetGroupDetailsByUserId).Methods("GET")
+	s.HandleFunc("/api/users/{userId}/balances", server.GetUserBalances).Methods("GET")
+	s.HandleFunc("/api/users/{userId}/balances/{otherUserId}/net", server.NetBalances).Methods("POST")
+	s.HandleFunc("/api/creategroup/{userId}", server.CreateGroup).Methods("POST")
+	s.HandleFunc("/api/addUsersToGroup/{groupId}", server.AddUsersToGroup).Methods("POST")
+	s.HandleFunc("/api/groups/{groupId}/rounding-policy", server.UpdateGroupRoundingPolicy).Methods("PUT")
+	s.HandleFunc("/api/rates", server.GetExchangeRate).Methods("GET")
+	s.HandleFunc("/api/groups/{groupId}/base-currency", server.UpdateGroupBaseCurrency).Methods("PUT")
+	s.HandleFunc("/api/groups/{groupId}/categories", server.GetGroupCategories).Methods("GET")
+	s.HandleFunc("/api/groups/{groupId}/categories", server.AddGroupCategory).Methods("POST")
+	s.HandleFunc("/api/groups/{groupId}/reports/categories", server.GetCategoryReport).Methods("GET")
+	s.HandleFunc("/api/groups/{groupId}/settle-plan", server.GetSettlePlan).Methods("GET")
+	s.HandleFunc("/api/groups/{groupId}/settle", server.SettleUp).Methods("POST")
+	s.HandleFunc("/api/groupUsers/{groupId}", server.GetGroupUsers).Methods("GET")
+	s.HandleFunc("/api/notGroupUsers/{groupId}", server.GetNotGroupUsers).Methods("GET")
+	s.HandleFunc("/api/addExpense/{groupId}", server.AddExpense).Methods("POST")
+	s.HandleFunc("/api/groups/{groupId}/recurring-expenses", server.CreateRecurringExpense).Methods("POST")
+	s.HandleFunc("/api/groups/{groupId}/recurring-expenses", server.GetRecurringExpenses).Methods("GET")
+	s.HandleFunc("/api/recurring-expenses/{recurringExpenseId}", server.StopRecurringExpense).Methods("DELETE")
+	s.HandleFunc("/api/expenses/{expenseId}", server.UpdateExpense).Methods("PUT")
+	s.HandleFunc("/api/expenses/{expenseId}", server.DeleteExpense).Methods("DELETE")
+	s.HandleFunc("/api/items/{groupId}", server.GetItemsByGroupId).Methods("GET")
+	s.HandleFunc("/api/settlements/{groupId}/{userId}", server.GetSettlements).Methods("POST")
+	s.HandleFunc("/api/memories/{groupId}", server.GetMemoriesHandler).Methods("GET")
+	s.HandleFunc("/api/memories/upload", server.UploadMemoryHandler).Methods("POST")
+	s.HandleFunc("/api/memories/{memoryId}", server.DeleteMemoryHandler).Methods("DELETE")
+	s.HandleFunc("/api/getTransactions/{groupId}", server.GetTransactions).Methods("GET")
+	s.HandleFunc("/api/transactions/{transactionId}", server.ReverseTransaction).Methods("DELETE")
 
 	return r
 }
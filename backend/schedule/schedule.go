@@ -0,0 +1,220 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cadences with a fixed period, anchored at a start time. Anything else is
+// parsed as a cron expression.
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+)
+
+// searchLimit bounds how far ahead a cron expression is searched, so that
+// expressions which never match (like February 30th) can't loop forever
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// Schedule produces the times at which something recurs
+type Schedule interface {
+	// Next returns the first occurrence strictly after after, or the zero
+	// time if there is none
+	Next(after time.Time) time.Time
+}
+
+// Parse reads a cadence: daily, weekly or monthly recur at start's time of day
+// every day, week or month from start; anything else is read as a standard
+// five-field cron expression (minute hour day-of-month month day-of-week)
+// evaluated in UTC
+func Parse(cadence string, start time.Time) (Schedule, error) {
+	switch strings.ToLower(strings.TrimSpace(cadence)) {
+	case Daily:
+		return interval{start: start, days: 1}, nil
+	case Weekly:
+		return interval{start: start, days: 7}, nil
+	case Monthly:
+		return interval{start: start, months: 1}, nil
+	}
+
+	cron, err := parseCron(cadence)
+	if err != nil {
+		return nil, err
+	}
+	if cron.Next(start).IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", cadence)
+	}
+	return cron, nil
+}
+
+// First returns the first occurrence of s at or after start
+func First(s Schedule, start time.Time) time.Time {
+	return s.Next(start.Add(-time.Nanosecond))
+}
+
+// interval recurs every fixed number of days or months from start. Monthly
+// occurrences keep start's day of the month, falling back to the last day of
+// shorter months.
+type interval struct {
+	start  time.Time
+	days   int
+	months int
+}
+
+func (i interval) at(n int) time.Time {
+	if i.days > 0 {
+		return i.start.AddDate(0, 0, n*i.days)
+	}
+
+	year, month, day := i.start.Date()
+	first := time.Date(year, month+time.Month(n*i.months), 1, 0, 0, 0, 0, i.start.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	hour, min, sec := i.start.Clock()
+	return time.Date(first.Year(), first.Month(), day, hour, min, sec, i.start.Nanosecond(), i.start.Location())
+}
+
+func (i interval) Next(after time.Time) time.Time {
+	if after.Before(i.start) {
+		return i.start
+	}
+
+	// Estimate how many periods have passed, then step to the first
+	// occurrence after the given time
+	var n int
+	if i.days > 0 {
+		n = int(after.Sub(i.start) / (time.Duration(i.days) * 24 * time.Hour))
+	} else {
+		n = ((after.Year()-i.start.Year())*12 + int(after.Month()-i.start.Month())) / i.months
+	}
+	if n > 0 {
+		n--
+	}
+	for !i.at(n).After(after) {
+		n++
+	}
+	return i.at(n)
+}
+
+// cron matches times whose fields are all in the allowed sets. As in standard
+// cron, when both day fields are restricted a day matching either one matches.
+type cron struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek map[int]bool
+	anyDayOfMonth, anyDayOfWeek                     bool
+}
+
+func parseCron(spec string) (*cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cadence %q, expected daily, weekly, monthly or a five-field cron expression", spec)
+	}
+
+	var c cron
+	var err error
+	if c.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if c.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if c.daysOfMonth, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	if c.months, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if c.daysOfWeek, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %w", err)
+	}
+	// Both 0 and 7 mean Sunday
+	if c.daysOfWeek[7] {
+		c.daysOfWeek[0] = true
+	}
+	c.anyDayOfMonth = strings.HasPrefix(fields[2], "*")
+	c.anyDayOfWeek = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+// parseField reads a comma-separated list of *, n, a-b, */step and a-b/step
+// terms into the set of values they allow
+func parseField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, term := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(term, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return nil, fmt.Errorf("invalid value %q", lowPart)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return nil, fmt.Errorf("invalid value %q", highPart)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is outside %d-%d", rangePart, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (c *cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.daysOfMonth[t.Day()]
+	dayOfWeek := c.daysOfWeek[int(t.Weekday())]
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func (c *cron) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(searchLimit)
+
+	// Skip whole months, days and hours that can't match before checking
+	// individual minutes
+	for t.Before(limit) {
+		if !c.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
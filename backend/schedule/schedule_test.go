@@ -0,0 +1,127 @@
+package schedule_test
+
+import (
+	"go-splitwise/schedule"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		cadence string
+		start   time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "daily keeps the start's time of day",
+			cadence: "daily",
+			start:   date(2026, 2, 27, 9, 30),
+			want:    []time.Time{date(2026, 2, 27, 9, 30), date(2026, 2, 28, 9, 30), date(2026, 3, 1, 9, 30)},
+		},
+		{
+			name:    "weekly",
+			cadence: "Weekly",
+			start:   date(2026, 12, 28, 8, 0),
+			want:    []time.Time{date(2026, 12, 28, 8, 0), date(2027, 1, 4, 8, 0), date(2027, 1, 11, 8, 0)},
+		},
+		{
+			name:    "monthly from the 31st falls back to the last day of shorter months",
+			cadence: "monthly",
+			start:   date(2026, 1, 31, 12, 0),
+			want: []time.Time{
+				date(2026, 1, 31, 12, 0), date(2026, 2, 28, 12, 0), date(2026, 3, 31, 12, 0),
+				date(2026, 4, 30, 12, 0), date(2026, 5, 31, 12, 0),
+			},
+		},
+		{
+			name:    "monthly from the 29th in a leap year",
+			cadence: "monthly",
+			start:   date(2028, 1, 29, 0, 0),
+			want:    []time.Time{date(2028, 1, 29, 0, 0), date(2028, 2, 29, 0, 0), date(2028, 3, 29, 0, 0)},
+		},
+		{
+			name:    "cron on the last possible day of the month skips shorter months",
+			cadence: "0 9 31 * *",
+			start:   date(2026, 1, 15, 0, 0),
+			want:    []time.Time{date(2026, 1, 31, 9, 0), date(2026, 3, 31, 9, 0), date(2026, 5, 31, 9, 0)},
+		},
+		{
+			name:    "cron on February 29th",
+			cadence: "0 0 29 2 *",
+			start:   date(2026, 1, 1, 0, 0),
+			want:    []time.Time{date(2028, 2, 29, 0, 0)},
+		},
+		{
+			name:    "cron on weekdays",
+			cadence: "30 18 * * 1-5",
+			start:   date(2026, 10, 16, 19, 0), // a Friday, after 18:30
+			want:    []time.Time{date(2026, 10, 19, 18, 30), date(2026, 10, 20, 18, 30)},
+		},
+		{
+			name:    "cron on Sundays written as 7",
+			cadence: "0 10 * * 7",
+			start:   date(2026, 10, 17, 0, 0),
+			want:    []time.Time{date(2026, 10, 18, 10, 0), date(2026, 10, 25, 10, 0)},
+		},
+		{
+			name:    "cron with both day fields matches either",
+			cadence: "0 0 1 * 1",
+			start:   date(2026, 6, 1, 0, 0),
+			want:    []time.Time{date(2026, 6, 1, 0, 0), date(2026, 6, 8, 0, 0), date(2026, 6, 15, 0, 0)},
+		},
+		{
+			name:    "cron with steps and lists",
+			cadence: "*/20 8,20 * * *",
+			start:   date(2026, 3, 1, 8, 30),
+			want:    []time.Time{date(2026, 3, 1, 8, 40), date(2026, 3, 1, 20, 0), date(2026, 3, 1, 20, 20)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := schedule.Parse(tt.cadence, tt.start)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.cadence, err)
+			}
+
+			got := schedule.First(s, tt.start)
+			for i, want := range tt.want {
+				if i > 0 {
+					got = s.Next(got)
+				}
+				if !got.Equal(want) {
+					t.Fatalf("occurrence %d is %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidCadences(t *testing.T) {
+	start := date(2026, 1, 1, 0, 0)
+	for _, cadence := range []string{
+		"",
+		"fortnightly",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+	} {
+		if _, err := schedule.Parse(cadence, start); err == nil {
+			t.Errorf("Parse(%q) succeeded", cadence)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-import React, { useState } from "react";
+import React, { useState, useEffect } from "react";
 import DropdownMenu from "./DropDownMenu";
 
 const Expense = ({ users, groupId, onExpenseAdded, onCancel }) => {
@@ -7,9 +7,28 @@ const Expense = ({ users, groupId, onExpenseAdded, onCancel }) => {
   const [payerId, setPayerId] = useState("");
   const [amount, setAmount] = useState("");
   const [description, setDescription] = useState("");
+  const [category, setCategory] = useState("");
+  const [expenseDate, setExpenseDate] = useState(new Date().toISOString().slice(0, 10));
+  const [categories, setCategories] = useState([]);
   const [isLoading, setIsLoading] = useState(false);
   const [toast, setToast] = useState({ show: false, message: "", type: "" });
 
+  useEffect(() => {
+    const fetchCategories = async () => {
+      try {
+        const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/groups/${groupId}/categories`, {
+          credentials: "include",
+        });
+        if (response.ok) {
+          setCategories(await response.json());
+        }
+      } catch (error) {
+        console.error("Error fetching categories:", error);
+      }
+    };
+    fetchCategories();
+  }, [groupId]);
+
   if (!users) return null;
 
   const findUserName = (userId) => {
@@ -99,6 +118,8 @@ const Expense = ({ users, groupId, onExpenseAdded, onCancel }) => {
       amount: parseInt(amount),
       payer_id: payerId,
       description: description,
+      category: category,
+      expense_date: expenseDate,
       expense_type: expenseType,
       user_shares: checkedUsers.map((userId) => ({
         user_id: userId,
@@ -109,6 +130,7 @@ const Expense = ({ users, groupId, onExpenseAdded, onCancel }) => {
     try {
       const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/addExpense/${groupId}`, {
         method: "POST",
+        credentials: "include",
         headers: {
           "Content-Type": "application/json",
         },
@@ -132,6 +154,7 @@ const Expense = ({ users, groupId, onExpenseAdded, onCancel }) => {
         setCheckedUsers([]);
         setAmount("");
         setDescription("");
+        setCategory("");
       }
       
     } catch (error) {
@@ -214,6 +237,32 @@ const Expense = ({ users, groupId, onExpenseAdded, onCancel }) => {
           />
         </div>
         
+        {/* Category - Full width */}
+        <div className="col-span-2">
+          <label htmlFor="category" className="block text-sm font-medium text-gray-700 mb-1">
+            Category <span className="text-xs text-gray-500">(optional)</span>
+          </label>
+          <DropdownMenu 
+            options={categories.map((c) => ({ label: c.name.charAt(0).toUpperCase() + c.name.slice(1), value: c.name }))} 
+            dropdownId="category"
+            onSelect={(value) => setCategory(value)}
+          />
+        </div>
+
+        {/* Date - Full width */}
+        <div className="col-span-2">
+          <label htmlFor="expense-date" className="block text-sm font-medium text-gray-700 mb-1">
+            Date
+          </label>
+          <input 
+            type="date" 
+            id="expense-date" 
+            value={expenseDate}
+            onChange={(e) => setExpenseDate(e.target.value)}
+            className="w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none"
+          />
+        </div>
+
         {/* Amount - First column */}
         <div>
           <label htmlFor="expense-amount" className="block text-sm font-medium text-gray-700 mb-1">
@@ -12,6 +12,7 @@ const GroupForm = ({ onSuccess, onClose }) => {
         try {
             const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/creategroup/${currentUser.id}`, {
                 method: "POST",
+                credentials: "include",
                 headers: {
                     "Content-Type": "application/json",
                 },
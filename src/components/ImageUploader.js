@@ -65,6 +65,7 @@ const ImageUploader = ({ groupId, onUploadSuccess, onCancel }) => {
             // Replace with your actual API endpoint
             const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/memories/upload`, {
                 method: 'POST',
+                credentials: "include",
                 body: formData,
             });
             
@@ -1,4 +1,5 @@
 import React, { useState } from "react";
+import { currencySymbol } from "../currency";
 
 const Item = ({ item, users }) => {
   const [isExpanded, setIsExpanded] = useState(false);
@@ -20,7 +21,14 @@ const Item = ({ item, users }) => {
       >
         <div className="flex justify-between items-center">
           <div className="flex-grow">
-            <h3 className="text-base font-medium text-gray-900">{item.description}</h3>
+            <h3 className="text-base font-medium text-gray-900">
+              {item.description}
+              {item.category && (
+                <span className="ml-2 px-1.5 py-0.5 text-xs font-normal text-gray-600 bg-gray-100 rounded capitalize">
+                  {item.category}
+                </span>
+              )}
+            </h3>
             <div className="flex items-center text-xs text-gray-600 mt-0.5">
               <svg xmlns="http://www.w3.org/2000/svg" className="h-3 w-3 mr-1 text-gray-500" viewBox="0 0 20 20" fill="currentColor">
                 <path fillRule="evenodd" d="M10 9a3 3 0 100-6 3 3 0 000 6zm-7 9a7 7 0 1114 0H3z" clipRule="evenodd" />
@@ -30,7 +38,7 @@ const Item = ({ item, users }) => {
           </div>
           
           <div className="flex items-center">
-            <div className="text-base font-bold text-gray-800 mr-2">₹{Math.abs(item.amount).toFixed(2)}</div>
+            <div className="text-base font-bold text-gray-800 mr-2">{currencySymbol(item.currency)}{Math.abs(item.amount).toFixed(2)}</div>
             <svg
               xmlns="http://www.w3.org/2000/svg"
               className={`h-4 w-4 text-gray-400 transition-transform ${isExpanded ? 'transform rotate-180' : ''}`}
@@ -64,9 +72,9 @@ const Item = ({ item, users }) => {
           </div>
           
           {/* Date if available */}
-          {item.date && (
+          {(item.expense_date || item.date) && (
             <div className="text-xs text-gray-400 mt-1 text-right">
-              {new Date(item.date).toLocaleString([], {
+              {new Date(item.expense_date ? `${item.expense_date}T00:00:00` : item.date).toLocaleString([], {
                 year: 'numeric',
                 month: 'short',
                 day: 'numeric'
@@ -1,29 +1,74 @@
 import React, { useState, useEffect } from "react";
 import Item from "./Item";
 
+const PAGE_SIZE = 20;
+
 const Items = ({ groupId, users }) => {
   const [items, setItems] = useState([]);
+  const [nextCursor, setNextCursor] = useState("");
+  const [search, setSearch] = useState("");
   const [loading, setLoading] = useState(true);
+  const [loadingMore, setLoadingMore] = useState(false);
+
+  const fetchPage = async (cursor) => {
+    const params = new URLSearchParams({ limit: PAGE_SIZE });
+    if (search.trim()) params.set("q", search.trim());
+    if (cursor) params.set("cursor", cursor);
+
+    const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/items/${groupId}?${params}`, { credentials: "include" });
+    if (!response.ok) {
+      throw new Error("Failed to fetch expenses");
+    }
+    return response.json();
+  };
 
   useEffect(() => {
     const fetchItems = async () => {
       setLoading(true);
       try {
-        const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/items/${groupId}`);
-        const data = await response.json();
-        setItems(data);
+        const data = await fetchPage("");
+        setItems(data.items);
+        setNextCursor(data.next_cursor || "");
       } catch (error) {
         console.error("Error fetching items:", error);
       } finally {
         setLoading(false);
       }
     };
-    fetchItems();
-  }, [groupId]);
+
+    // Wait for the user to stop typing before searching
+    const timer = setTimeout(fetchItems, 300);
+    return () => clearTimeout(timer);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [groupId, search]);
+
+  const loadMore = async () => {
+    setLoadingMore(true);
+    try {
+      const data = await fetchPage(nextCursor);
+      setItems((prevItems) => [...prevItems, ...data.items]);
+      setNextCursor(data.next_cursor || "");
+    } catch (error) {
+      console.error("Error fetching items:", error);
+    } finally {
+      setLoadingMore(false);
+    }
+  };
+
+  const searchBox = (
+    <input
+      type="search"
+      placeholder="Search expenses"
+      value={search}
+      onChange={(e) => setSearch(e.target.value)}
+      className="w-full mb-4 px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none"
+    />
+  );
 
   if (loading) {
     return (
       <div className="space-y-4">
+        {searchBox}
         {[...Array(3)].map((_, index) => (
           <div key={index} className="animate-pulse bg-white rounded-lg shadow-sm border-l-4 border-gray-200 p-4">
             <div className="flex justify-between items-start mb-3">
@@ -47,6 +92,14 @@ const Items = ({ groupId, users }) => {
   }
 
   if (!items || items.length === 0) {
+    if (search.trim()) {
+      return (
+        <div>
+          {searchBox}
+          <div className="text-center py-10 bg-gray-50 rounded-lg text-gray-500">No expenses match your search.</div>
+        </div>
+      );
+    }
     return (
       <div className="text-center py-10 bg-gray-50 rounded-lg">
         <svg xmlns="http://www.w3.org/2000/svg" className="h-12 w-12 mx-auto text-gray-400 mb-3" fill="none" viewBox="0 0 24 24" stroke="currentColor">
@@ -60,11 +113,21 @@ const Items = ({ groupId, users }) => {
 
   return (
     <div className="space-y-4">
+      {searchBox}
       {items.map((item,index) => (
-        <div key={item.id || index}>
+        <div key={item.expense_id || index}>
           <Item item={item} users={users} />
         </div>
       ))}
+      {nextCursor && (
+        <button
+          onClick={loadMore}
+          disabled={loadingMore}
+          className="w-full py-2 text-sm font-medium text-indigo-600 bg-white border border-gray-200 rounded-md hover:bg-gray-50 disabled:opacity-50"
+        >
+          {loadingMore ? "Loading..." : "Load more"}
+        </button>
+      )}
     </div>
   );
 };
@@ -18,7 +18,7 @@ const Memories = ({ groupId, onMemoryAdded }) => {
         setLoading(true);
         try {
             // Replace with your actual API endpoint
-            const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/memories/${groupId}`);
+            const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/memories/${groupId}`, { credentials: "include" });
             if (!response.ok) {
                 throw new Error('Failed to fetch memories');
             }
@@ -37,6 +37,7 @@ const Memories = ({ groupId, onMemoryAdded }) => {
         try {
             const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/memories/${memoryId}`, {
                 method: 'DELETE',
+                credentials: "include",
             });
             
             if (!response.ok) {
@@ -2,26 +2,55 @@ import React from "react";
 import { useState, useEffect } from "react"; 
 import { useParams } from "react-router-dom"; 
 import { useAuth } from "../auth/AuthContext";  
+import { currencySymbol } from "../currency";
 
 const RecentTransactions = ({users, refreshTransactions}) => {
     const params = useParams();
     const groupId = params.groupId;
     const [transactions, setTransactions] = useState([]);
+    const [nextCursor, setNextCursor] = useState("");
+    const [currency, setCurrency] = useState("");
     const [loading, setLoading] = useState(true);
+    const [loadingMore, setLoadingMore] = useState(false);
     const { currentUser } = useAuth();
     
+    const fetchPage = async (cursor) => {
+        const params = new URLSearchParams({ limit: 20 });
+        if (cursor) params.set("cursor", cursor);
+
+        const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/getTransactions/${groupId}?${params}`, { credentials: "include" });
+        if (!response.ok) {
+            throw new Error("Failed to fetch transactions");
+        }
+        return response.json();
+    };
+
     const fetchTransactions = async () => {
         setLoading(true);
         try {
-            const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/getTransactions/${groupId}`);
-            const data = await response.json();
-            setTransactions(data);
+            const data = await fetchPage("");
+            setTransactions(data.transactions);
+            setCurrency(data.currency);
+            setNextCursor(data.next_cursor || "");
         } catch(error) {
             console.error("Error fetching transactions:", error);
         } finally {
             setLoading(false);
         }
     }
+
+    const loadMore = async () => {
+        setLoadingMore(true);
+        try {
+            const data = await fetchPage(nextCursor);
+            setTransactions((prevTransactions) => [...prevTransactions, ...data.transactions]);
+            setNextCursor(data.next_cursor || "");
+        } catch(error) {
+            console.error("Error fetching transactions:", error);
+        } finally {
+            setLoadingMore(false);
+        }
+    };
     
     const findUserName = (userId) => {
         const user = users.find(u => u.id === userId);
@@ -127,7 +156,7 @@ const RecentTransactions = ({users, refreshTransactions}) => {
                         const isUserReceiver = transaction.user_id === currentUser.id;
                         
                         return (
-                            <div key={transaction.id} className="bg-white border border-gray-100 rounded-lg p-2 hover:bg-gray-50 transition-colors">
+                            <div key={transaction.id} className={`bg-white border border-gray-100 rounded-lg p-2 hover:bg-gray-50 transition-colors ${transaction.reversed_at ? 'opacity-60' : ''}`}>
                                 <div className="flex items-center space-x-3">
                                     {getTransactionIcon(transaction)}
                                     
@@ -135,15 +164,19 @@ const RecentTransactions = ({users, refreshTransactions}) => {
                                         <div className="flex items-center justify-between">
                                             <div className="text-xs text-gray-700">
                                                 {isUserPayer ? (
-                                                    <>You paid <span className="text-red-600 font-medium">₹{transaction.amount}</span> to {findUserName(transaction.user_id)}</>
+                                                    <>You paid <span className="text-red-600 font-medium">{currencySymbol(currency)}{transaction.amount}</span> to {findUserName(transaction.user_id)}</>
                                                 ) : isUserReceiver ? (
-                                                    <><span className="text-green-600 font-medium">₹{transaction.amount}</span> received from {findUserName(transaction.payer_id)}</>
+                                                    <><span className="text-green-600 font-medium">{currencySymbol(currency)}{transaction.amount}</span> received from {findUserName(transaction.payer_id)}</>
                                                 ) : (
-                                                    <>{findUserName(transaction.payer_id)} paid <span className="text-indigo-600 font-medium">₹{transaction.amount}</span> to {findUserName(transaction.user_id)}</>
+                                                    <>{findUserName(transaction.payer_id)} paid <span className="text-indigo-600 font-medium">{currencySymbol(currency)}{transaction.amount}</span> to {findUserName(transaction.user_id)}</>
                                                 )}
                                             </div>
                                             <div className="text-xs text-gray-400 ml-2">
-                                                {formatDate(transaction.created_at)}
+                                                {transaction.reversed_at ? (
+                                                    <span className="text-gray-500 font-medium">Reversed</span>
+                                                ) : transaction.kind === 'netting' ? (
+                                                    <>Netted across groups · {formatDate(transaction.created_at)}</>
+                                                ) : formatDate(transaction.created_at)}
                                             </div>
                                         </div>
                                     </div>
@@ -151,6 +184,15 @@ const RecentTransactions = ({users, refreshTransactions}) => {
                             </div>
                         );
                     })}
+                    {nextCursor && (
+                        <button
+                            onClick={loadMore}
+                            disabled={loadingMore}
+                            className="w-full py-2 text-xs font-medium text-indigo-600 hover:bg-gray-50 rounded-lg disabled:opacity-50"
+                        >
+                            {loadingMore ? "Loading..." : "Load more"}
+                        </button>
+                    )}
                 </div>
             )}
         </div>
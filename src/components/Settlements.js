@@ -1,5 +1,6 @@
 import React, { useState, useEffect, useMemo } from "react";
 import { useAuth } from "../auth/AuthContext";
+import { currencySymbol } from "../currency";
 
 const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) => {
   const { currentUser } = useAuth();
@@ -21,6 +22,7 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
         `${process.env.REACT_APP_BACKEND_URL}/api/settlements/${groupId}/${currentUser.id}`,
         {
           method: "POST",
+          credentials: "include",
           headers: {
             "Content-Type": "application/json",
           },
@@ -48,21 +50,21 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
   const confirmAndSettleUp = async () => {
     if (!confirmSettlement) return;
     
-    const { userId, amount } = confirmSettlement;
+    const { userId } = confirmSettlement;
     setSettlingUp(userId);
     setConfirmSettlement(null);
     
     try {
-      // Replace with your actual API endpoint for settling up
-      const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/insertTransactions/${groupId}`, {
+      const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/groups/${groupId}/settle`, {
         method: "POST",
+        credentials: "include",
         headers: {
           "Content-Type": "application/json",
         },
         body: JSON.stringify({
           payer_id: currentUser.id,
-          user_id: userId,
-          amount: Math.abs(amount)
+          receiver_id: userId,
+          settle_in_full: true
         }),
       });
       
@@ -104,6 +106,9 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
     };
   }, [settlements]);
 
+  // Every settlement is in the group's base currency
+  const symbol = currencySymbol(settlements?.[0]?.currency);
+
   if (isLoading) {
     return (
       <div className="mt-4 space-y-2 animate-pulse">
@@ -137,7 +142,7 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
             <span className={`ml-3 px-2 py-1 text-xs font-medium rounded-full ${
               isPositiveBalance ? 'bg-green-100 text-green-800' : 'bg-red-100 text-red-800'
             }`}>
-              {isPositiveBalance ? 'You are owed' : 'You owe'} ₹{Math.abs(totalBalance)}
+              {isPositiveBalance ? 'You are owed' : 'You owe'} {symbol}{Math.abs(totalBalance)}
             </span>
           </div>
           <svg
@@ -193,7 +198,7 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
                     
                     <div className="flex items-center space-x-3">
                       {isPositive &&<div className={`font-medium ${isPositive ? "text-green-600" : "text-red-600"}`}>
-                        ₹{amount}
+                        {symbol}{amount}
                       </div>}
                       {!isPositive && (
                         <button
@@ -212,7 +217,7 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
                               Processing
                             </span>
                           ) : (
-                            `Pay ₹${amount}`
+                            `Pay ${symbol}${amount}`
                           )}
                         </button>
                       )}
@@ -237,12 +242,12 @@ const Settlements = ({ groupId, users, refreshItems, setRefreshTransactions }) =
               <div className="p-5">
                 <div className="flex items-center justify-center mb-5">
                 <div className="h-16 w-16 bg-indigo-100 rounded-full flex items-center justify-center">
-                  <span className="text-2xl font-bold text-indigo-600">₹</span>
+                  <span className="text-2xl font-bold text-indigo-600">{symbol}</span>
                 </div>
                 </div>
                 
                 <p className="text-center text-gray-700 mb-6">
-                  You're about to settle up <span className="font-medium text-gray-900">₹{Math.abs(confirmSettlement.amount).toFixed(2)}</span> with <span className="font-medium text-gray-900">{confirmSettlement.userName}</span>.
+                  You're about to settle up <span className="font-medium text-gray-900">{symbol}{Math.abs(confirmSettlement.amount).toFixed(2)}</span> with <span className="font-medium text-gray-900">{confirmSettlement.userName}</span>.
                 </p>
                 
                 <div className="flex space-x-3">
@@ -28,6 +28,7 @@ const Users = ({ groupId: propGroupId, inModal = false, onUsersAdded, onCancel }
         try {
             const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/addUsersToGroup/${groupId}`, {
                 method: "POST",
+                credentials: "include",
                 headers: {
                     "Content-Type": "application/json",
                 },
@@ -51,7 +52,7 @@ const Users = ({ groupId: propGroupId, inModal = false, onUsersAdded, onCancel }
         setLoading(true);
         const fetchGroupUsers = async () => {
             try {
-                const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/notGroupUsers/${groupId}`);
+                const response = await fetch(`${process.env.REACT_APP_BACKEND_URL}/api/notGroupUsers/${groupId}`, { credentials: "include" });
                 const data = await response.json();
                 setUsers(data);
             } catch (error) {
@@ -0,0 +1,13 @@
+// currencySymbol returns the symbol shown in front of amounts in the given
+// ISO currency code, falling back to the code itself when the browser has no
+// symbol for it
+export const currencySymbol = (code) => {
+  if (!code) return "";
+  try {
+    const parts = new Intl.NumberFormat(undefined, { style: "currency", currency: code }).formatToParts(0);
+    const symbol = parts.find(part => part.type === "currency");
+    return symbol ? symbol.value : code;
+  } catch (error) {
+    return code;
+  }
+};